@@ -1,12 +1,25 @@
 package haproxy
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"reflect"
-	"strings"
+)
+
+// unixPathLength is the size of fields that hold UNIX socket paths.
+const unixPathLength = 108
+
+// Sizes of address blocks, which are followed by TLVs if there are any. Both
+// encoding and decoding rely on them, so the declared length always matches
+// the number of bytes written.
+const (
+	ipv4AddressLength AddressLength = 2*net.IPv4len + 2*2
+	ipv6AddressLength AddressLength = 2*net.IPv6len + 2*2
+	unixAddressLength AddressLength = 2 * unixPathLength
 )
 
 type AddressFamily byte
@@ -34,6 +47,21 @@ const (
 	AddressFamilyUNIX
 )
 
+func (f AddressFamily) String() string {
+	switch f {
+	case AddressFamilyUNSPEC:
+		return "UNSPEC"
+	case AddressFamilyINET:
+		return "INET"
+	case AddressFamilyINET6:
+		return "INET6"
+	case AddressFamilyUNIX:
+		return "UNIX"
+	default:
+		return fmt.Sprintf("UNKNOWN(0x%02x)", byte(f))
+	}
+}
+
 type TransportProtocol byte
 
 const (
@@ -57,34 +85,68 @@ const (
 	TransportProtocolDGRAM
 )
 
+func (t TransportProtocol) String() string {
+	switch t {
+	case TransportProtocolUNSPEC:
+		return "UNSPEC"
+	case TransportProtocolSTREAM:
+		return "STREAM"
+	case TransportProtocolDGRAM:
+		return "DGRAM"
+	default:
+		return fmt.Sprintf("UNKNOWN(0x%02x)", byte(t))
+	}
+}
+
 type ProtocolByte struct {
 	AddressFamily     AddressFamily
 	TransportProtocol TransportProtocol
 }
 
 func (p *ProtocolByte) ReadFrom(r io.Reader) (n int64, err error) {
-	data := make([]byte, 1)
-	m, err := r.Read(data)
-	n += int64(m)
+	b, n, err := readByte(r)
 	if err != nil {
 		return n, err
 	}
 
-	p.AddressFamily = AddressFamily(data[0] >> 4)
-	p.TransportProtocol = TransportProtocol(data[0] & 0b1111)
+	p.AddressFamily = AddressFamily(b >> 4)
+	p.TransportProtocol = TransportProtocol(b & 0b1111)
 	return
 }
 
+// ValidateProtocolByte checks that the address family and the transport
+// protocol are among the values defined by the specification, the same way
+// receivers do, which allows senders that build headers manually to reject
+// invalid values before writing them. Combinations of defined values, such
+// as AddressFamilyINET with TransportProtocolUNSPEC, are valid.
+func ValidateProtocolByte(p ProtocolByte) error {
+	// Other values are unspecified and must not be emitted in version 2 of the
+	// protocol and must be rejected as invalid by receivers.
+	if p.AddressFamily != AddressFamilyUNSPEC && p.AddressFamily != AddressFamilyINET &&
+		p.AddressFamily != AddressFamilyINET6 && p.AddressFamily != AddressFamilyUNIX {
+		return fmt.Errorf("unsupported address family: expected 0x0 - 0x3, but got %s", p.AddressFamily)
+	}
+
+	// Other values are unspecified and must not be emitted in version 2 of the
+	// protocol and must be rejected as invalid by receivers.
+	if p.TransportProtocol != TransportProtocolUNSPEC && p.TransportProtocol != TransportProtocolSTREAM &&
+		p.TransportProtocol != TransportProtocolDGRAM {
+		return fmt.Errorf("unsupported transport protocol: expected 0x0 - 0x2, but got %s", p.TransportProtocol)
+	}
+
+	return nil
+}
+
 func (p ProtocolByte) WriteTo(w io.Writer) (n int64, err error) {
-	m, err := w.Write([]byte{byte(p.AddressFamily<<4) | byte(p.TransportProtocol)})
+	m, err := writeFull(w, []byte{byte(p.AddressFamily<<4) | byte(p.TransportProtocol)})
 	return int64(m), err
 }
 
-type AddressLength int16
+type AddressLength uint16
 
 func (a *AddressLength) ReadFrom(r io.Reader) (n int64, err error) {
 	data := make([]byte, 2)
-	m, err := r.Read(data)
+	m, err := io.ReadFull(r, data)
 	n += int64(m)
 	if err != nil {
 		return n, err
@@ -96,32 +158,71 @@ func (a *AddressLength) ReadFrom(r io.Reader) (n int64, err error) {
 }
 
 func (a AddressLength) WriteTo(w io.Writer) (n int64, err error) {
-	err = binary.Write(w, binary.BigEndian, a)
-	if err != nil {
-		return 0, err
-	}
+	m, err := writeFull(w, []byte{byte(a >> 8), byte(a)})
+	return int64(m), err
+}
 
-	return 2, nil // Since we have written address length which should be exactly 2 bytes long
+// UnsupportedAddressError is returned when an address of unexpected type is
+// used for writing a header.
+type UnsupportedAddressError struct {
+	Addr net.Addr
 }
 
-func getTransportProtocol(addr net.Addr) TransportProtocol {
+func (u UnsupportedAddressError) Error() string {
+	return fmt.Sprintf("address type %T is not supported", u.Addr)
+}
+
+func getTransportProtocol(addr net.Addr) (TransportProtocol, error) {
 	switch addr.(type) {
 	case *net.TCPAddr:
-		return TransportProtocolSTREAM
+		return TransportProtocolSTREAM, nil
 	case *net.UDPAddr:
-		return TransportProtocolDGRAM
+		return TransportProtocolDGRAM, nil
 	case *net.UnixAddr:
+		if addr.(*net.UnixAddr) == nil {
+			return TransportProtocolUNSPEC, &UnsupportedAddressError{addr}
+		}
+
 		if addr.Network() == "unixgram" {
-			return TransportProtocolDGRAM
+			return TransportProtocolDGRAM, nil
 		}
 
-		return TransportProtocolSTREAM
+		// The protocol has no value for SOCK_SEQPACKET, so "unixpacket" is
+		// sent as a connection-oriented stream and decoded as "unix"
+		return TransportProtocolSTREAM, nil
 	default:
-		panic("address type is not supported")
+		return TransportProtocolUNSPEC, &UnsupportedAddressError{addr}
+	}
+}
+
+// getPairTransportProtocol returns the transport protocol of both addresses,
+// which must agree on it, since the header has room for a single one.
+func getPairTransportProtocol(src, dst net.Addr) (TransportProtocol, error) {
+	source, err := getTransportProtocol(src)
+	if err != nil {
+		return source, err
+	}
+
+	destination, err := getTransportProtocol(dst)
+	if err != nil {
+		return destination, err
+	}
+
+	if source != destination {
+		return TransportProtocolUNSPEC, fmt.Errorf(
+			"expected addresses of the same transport protocol, but got source %s (%s) and destination %s (%s)",
+			src, source, dst, destination,
+		)
 	}
+
+	return source, nil
 }
 
 func WrapAddress(src, dst net.Addr) (ProxyAddress, error) {
+	if src == nil || dst == nil {
+		return nil, fmt.Errorf("expected all addresses to present, got source %s and destination %s", src, dst)
+	}
+
 	if reflect.TypeOf(src) != reflect.TypeOf(dst) {
 		return nil, fmt.Errorf(
 			"expected source and destination addresses to be of the same type, but got source %s and destination %s",
@@ -129,54 +230,66 @@ func WrapAddress(src, dst net.Addr) (ProxyAddress, error) {
 		)
 	}
 
-	if src == nil || dst == nil {
-		return nil, fmt.Errorf("expected all addresses to present, got source %s and destination %s", src, dst)
-	}
-
 	switch src.(type) {
 	case *net.TCPAddr, *net.UDPAddr:
-		if strings.Count(src.String(), ":") > 1 {
-			// Address is IPv6
-			return &IPv6Address{
-				SourceAddr:      src,
-				DestinationAddr: dst,
-			}, nil
-		} else if strings.Count(src.String(), ".") == 3 {
+		sourceIP, destinationIP := getIP(src), getIP(dst)
+		if len(sourceIP) == 0 || len(destinationIP) == 0 {
+			return nil, fmt.Errorf("expected both addresses to have IP, got source %s and destination %s", src, dst)
+		}
+
+		// IPv4-mapped IPv6 addresses are treated as IPv4
+		if (sourceIP.To4() != nil) != (destinationIP.To4() != nil) {
+			return nil, fmt.Errorf("expected addresses of the same family, but got source %s and destination %s", src, dst)
+		}
+
+		if sourceIP.To4() != nil {
 			// Address is IPv4
 			return &IPv4Address{
 				SourceAddr:      src,
 				DestinationAddr: dst,
 			}, nil
 		}
+
+		// Address is IPv6
+		return &IPv6Address{
+			SourceAddr:      src,
+			DestinationAddr: dst,
+		}, nil
 	case *net.UnixAddr:
 		// Address is Unix
-		return &UnixAddr{
+		address := &UnixAddr{
 			SourceAddr:      src.(*net.UnixAddr),
 			DestinationAddr: dst.(*net.UnixAddr),
-		}, nil
+		}
+
+		err := address.validate()
+		if err != nil {
+			return nil, err
+		}
+
+		return address, nil
 	}
 
 	return nil, fmt.Errorf("address %s (%s) is not supported", src.String(), reflect.TypeOf(src).String())
 }
 
-func readPort(r io.Reader) (uint16, int, error) {
-	port := make([]byte, 2)
-	n, err := r.Read(port)
-	if err != nil {
-		return 0, n, err
-	}
+func getIP(addr net.Addr) net.IP {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		if addr == nil {
+			return nil
+		}
 
-	return binary.BigEndian.Uint16(port), n, nil
-}
+		return addr.IP
+	case *net.UDPAddr:
+		if addr == nil {
+			return nil
+		}
 
-func readIP(r io.Reader, length int) (*net.IP, int, error) {
-	ip := make([]byte, length)
-	n, err := r.Read(ip)
-	if err != nil {
-		return nil, n, err
+		return addr.IP
+	default:
+		return nil
 	}
-
-	return (*net.IP)(&ip), n, nil
 }
 
 type ipReadResult struct {
@@ -187,35 +300,34 @@ type ipReadResult struct {
 }
 
 func readIPsAndPorts(r io.Reader, addressLength int) (*ipReadResult, int, error) {
-	m, n := 0, 0
-	var err error
-	result := &ipReadResult{}
-
-	result.sourceIP, n, err = readIP(r, addressLength)
-	m += n
+	// Both addresses and ports are read at once, and the addresses keep
+	// referring to the same allocation
+	data := make([]byte, 2*addressLength+4)
+	m, err := io.ReadFull(r, data)
 	if err != nil {
 		return nil, m, err
 	}
 
-	result.destinationIP, n, err = readIP(r, addressLength)
-	m += n
-	if err != nil {
-		return nil, m, err
-	}
+	sourceIP := unmapIPv4(data[:addressLength:addressLength])
+	destinationIP := unmapIPv4(data[addressLength : 2*addressLength : 2*addressLength])
 
-	result.sourcePort, n, err = readPort(r)
-	m += n
-	if err != nil {
-		return nil, m, err
-	}
+	return &ipReadResult{
+		sourceIP:        &sourceIP,
+		destinationIP:   &destinationIP,
+		sourcePort:      binary.BigEndian.Uint16(data[2*addressLength:]),
+		destinationPort: binary.BigEndian.Uint16(data[2*addressLength+2:]),
+	}, m, nil
+}
 
-	result.destinationPort, n, err = readPort(r)
-	m += n
-	if err != nil {
-		return nil, m, err
+// unmapIPv4 returns IPv4-mapped IPv6 addresses, like ::ffff:192.168.0.1, in
+// the 4-byte form, so that decoded IPs of IPv4 clients are the same whether
+// the proxy sent them over IPv4 or IPv6. Other addresses are returned as is.
+func unmapIPv4(ip net.IP) net.IP {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
 	}
 
-	return result, m, nil
+	return ip
 }
 
 type unixReadResult struct {
@@ -224,79 +336,121 @@ type unixReadResult struct {
 }
 
 func readUnix(r io.Reader) (*unixReadResult, int, error) {
-	result := &unixReadResult{
-		SourceAddr:      make([]byte, 108),
-		DestinationAddr: make([]byte, 108),
-	}
-
-	n, err := r.Read(result.SourceAddr)
+	// A single Read may return less than asked for, which would shift the
+	// destination path, so both paths are read in full at once
+	data := make([]byte, 216)
+	n, err := io.ReadFull(r, data)
 	if err != nil {
 		return nil, n, err
 	}
 
-	m, err := r.Read(result.DestinationAddr)
-	n += m
-	if err != nil {
-		return nil, n, err
+	return &unixReadResult{
+		SourceAddr:      data[:108:108],
+		DestinationAddr: data[108:],
+	}, n, nil
+}
+
+// unixPath returns the path stored in a fixed-size field, which is padded
+// with NUL bytes.
+func unixPath(data []byte) string {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
 	}
 
-	return result, n, nil
+	return string(data)
 }
 
-func writePorts(w io.Writer, src, dst net.Addr) (m int64, err error) {
-	err = binary.Write(w, binary.BigEndian, getPort(src))
+// appendPorts appends source and destination ports in network byte order.
+func appendPorts(dst []byte, src, dest net.Addr) ([]byte, error) {
+	sourcePort, err := getPort(src)
 	if err != nil {
-		return m, err
+		return dst, err
 	}
-	m += 2 // Source port length
 
-	err = binary.Write(w, binary.BigEndian, getPort(dst))
+	destinationPort, err := getPort(dest)
 	if err != nil {
-		return m, err
+		return dst, err
 	}
-	m += 2 // Destination port length
 
-	return
+	return append(dst, byte(sourcePort>>8), byte(sourcePort), byte(destinationPort>>8), byte(destinationPort)), nil
 }
 
-func addressToBytes(addr net.Addr) []byte {
-	switch addr.(type) {
-	case *net.TCPAddr:
-		return alignIP(addr.(*net.TCPAddr).IP)
-	case *net.UDPAddr:
-		return alignIP(addr.(*net.UDPAddr).IP)
+// appendAddress appends the IP address of TCP or UDP address as length bytes,
+// or the path of UNIX address padded with NUL bytes to a fixed-size field.
+func appendAddress(dst []byte, addr net.Addr, length int) ([]byte, error) {
+	switch addr := addr.(type) {
+	case *net.TCPAddr, *net.UDPAddr:
+		return appendIP(dst, getIP(addr), length)
 	case *net.UnixAddr:
-		data := make([]byte, 108)
-		copy(data, addr.String())
-		return data
+		if addr == nil {
+			return dst, fmt.Errorf("expected address to present, got nil %T", addr)
+		}
+
+		if len(addr.Name) > length {
+			return dst, fmt.Errorf("address path is %d bytes long, but at most %d bytes are allowed", len(addr.Name), length)
+		}
+
+		dst = append(dst, addr.Name...)
+		return append(dst, make([]byte, length-len(addr.Name))...), nil
 	default:
-		panic("address type is not supported")
+		return dst, &UnsupportedAddressError{addr}
 	}
 }
 
-func alignIP(ip net.IP) []byte {
-	if len(ip) < 16 {
-		return append(make([]byte, 16-len(ip)), ip...)
+// appendIP appends ip as length bytes. IPv4 addresses are canonicalized, so
+// both 4-byte and 16-byte forms of them are written the same way. IPs of
+// other lengths, e.g. nil ones, are malformed and cannot be written.
+func appendIP(dst []byte, ip net.IP, length int) ([]byte, error) {
+	if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+		return dst, fmt.Errorf("IP address %v is %d bytes long, expected %d or %d", ip, len(ip), net.IPv4len, net.IPv6len)
 	}
 
-	return ip
+	if length == net.IPv4len {
+		ip4 := ip.To4()
+		if ip4 == nil {
+			return dst, fmt.Errorf("IP address %s is not an IPv4 address", ip)
+		}
+
+		return append(dst, ip4...), nil
+	}
+
+	return append(dst, ip.To16()...), nil
 }
 
-func getPort(addr net.Addr) uint16 {
-	switch addr.(type) {
+// getPort returns the port of TCP or UDP address. Other addresses, including
+// nil pointers, and ports that do not fit in two bytes are an error.
+func getPort(addr net.Addr) (uint16, error) {
+	var port int
+	switch addr := addr.(type) {
 	case *net.TCPAddr:
-		return uint16(addr.(*net.TCPAddr).Port)
+		if addr == nil {
+			return 0, fmt.Errorf("expected address to present, got nil %T", addr)
+		}
+
+		port = addr.Port
 	case *net.UDPAddr:
-		return uint16(addr.(*net.UDPAddr).Port)
+		if addr == nil {
+			return 0, fmt.Errorf("expected address to present, got nil %T", addr)
+		}
+
+		port = addr.Port
 	default:
-		panic("address type is not supported")
+		return 0, &UnsupportedAddressError{addr}
+	}
+
+	if port < 0 || port > math.MaxUint16 {
+		return 0, fmt.Errorf("port %d of address %s is out of range", port, addr)
 	}
+
+	return uint16(port), nil
 }
 
 type ProxyAddress interface {
 	io.WriterTo
+	appendTo(dst []byte) ([]byte, error)
 	getLength() AddressLength
-	getSignature() ProtocolByte
+	getSignature() (ProtocolByte, error)
+	getAddresses() (net.Addr, net.Addr)
 }
 
 type IPv4Address struct {
@@ -305,68 +459,103 @@ type IPv4Address struct {
 }
 
 func (a IPv4Address) WriteTo(w io.Writer) (m int64, err error) {
-	n, err := w.Write(addressToBytes(a.SourceAddr)[12:])
-	m += int64(n)
+	data, err := a.appendTo(nil)
 	if err != nil {
-		return m, err
+		return 0, err
 	}
 
-	n, err = w.Write(addressToBytes(a.DestinationAddr)[12:])
-	m += int64(n)
+	n, err := writeFull(w, data)
+	return int64(n), err
+}
+
+func (a IPv4Address) appendTo(dst []byte) ([]byte, error) {
+	start := len(dst)
+	dst, err := appendAddress(dst, a.SourceAddr, net.IPv4len)
 	if err != nil {
-		return m, err
+		return dst[:start], err
 	}
 
-	k, err := writePorts(w, a.SourceAddr, a.DestinationAddr)
-	m += k
+	dst, err = appendAddress(dst, a.DestinationAddr, net.IPv4len)
 	if err != nil {
-		return m, err
+		return dst[:start], err
 	}
 
-	return
+	dst, err = appendPorts(dst, a.SourceAddr, a.DestinationAddr)
+	if err != nil {
+		return dst[:start], err
+	}
+
+	return dst, nil
 }
 
 func (a IPv4Address) getLength() AddressLength {
-	return 12
+	return ipv4AddressLength
+}
+
+func (a IPv4Address) getSignature() (ProtocolByte, error) {
+	transport, err := getPairTransportProtocol(a.SourceAddr, a.DestinationAddr)
+	return ProtocolByte{AddressFamilyINET, transport}, err
 }
 
-func (a IPv4Address) getSignature() ProtocolByte {
-	return ProtocolByte{AddressFamilyINET, getTransportProtocol(a.SourceAddr)}
+func (a IPv4Address) getAddresses() (net.Addr, net.Addr) {
+	return a.SourceAddr, a.DestinationAddr
 }
 
+// IPv6Address holds addresses of a connection forwarded over IPv6. The header
+// has no room for IPv6 zones, so the Zone of link-local addresses is dropped
+// when the header is written and is always empty in decoded headers.
+//
+// Decoded IPs are 16 bytes long, except for IPv4-mapped addresses, which are
+// decoded in the 4-byte form, like IPs of IPv4Address are. This way an IPv4
+// client has the same IP regardless of the family the proxy used, and it is
+// equal to net.IPv4(...).To4() even when compared byte by byte. Such
+// addresses are written back in the mapped form.
 type IPv6Address struct {
 	SourceAddr      net.Addr
 	DestinationAddr net.Addr
 }
 
 func (a IPv6Address) WriteTo(w io.Writer) (m int64, err error) {
-	n, err := w.Write(addressToBytes(a.SourceAddr))
-	m += int64(n)
+	data, err := a.appendTo(nil)
 	if err != nil {
-		return m, err
+		return 0, err
 	}
 
-	n, err = w.Write(addressToBytes(a.DestinationAddr))
-	m += int64(n)
+	n, err := writeFull(w, data)
+	return int64(n), err
+}
+
+func (a IPv6Address) appendTo(dst []byte) ([]byte, error) {
+	start := len(dst)
+	dst, err := appendAddress(dst, a.SourceAddr, net.IPv6len)
 	if err != nil {
-		return m, err
+		return dst[:start], err
 	}
 
-	k, err := writePorts(w, a.SourceAddr, a.DestinationAddr)
-	m += k
+	dst, err = appendAddress(dst, a.DestinationAddr, net.IPv6len)
 	if err != nil {
-		return m, err
+		return dst[:start], err
 	}
 
-	return
+	dst, err = appendPorts(dst, a.SourceAddr, a.DestinationAddr)
+	if err != nil {
+		return dst[:start], err
+	}
+
+	return dst, nil
 }
 
 func (a IPv6Address) getLength() AddressLength {
-	return 36
+	return ipv6AddressLength
+}
+
+func (a IPv6Address) getSignature() (ProtocolByte, error) {
+	transport, err := getPairTransportProtocol(a.SourceAddr, a.DestinationAddr)
+	return ProtocolByte{AddressFamilyINET6, transport}, err
 }
 
-func (a IPv6Address) getSignature() ProtocolByte {
-	return ProtocolByte{AddressFamilyINET6, getTransportProtocol(a.SourceAddr)}
+func (a IPv6Address) getAddresses() (net.Addr, net.Addr) {
+	return a.SourceAddr, a.DestinationAddr
 }
 
 type UnixAddr struct {
@@ -375,25 +564,67 @@ type UnixAddr struct {
 }
 
 func (a UnixAddr) WriteTo(w io.Writer) (m int64, err error) {
-	n, err := w.Write(addressToBytes(a.SourceAddr))
-	m += int64(n)
+	data, err := a.appendTo(nil)
 	if err != nil {
-		return m, err
+		return 0, err
 	}
 
-	n, err = w.Write(addressToBytes(a.DestinationAddr))
-	m += int64(n)
+	n, err := writeFull(w, data)
+	return int64(n), err
+}
+
+func (a UnixAddr) appendTo(dst []byte) ([]byte, error) {
+	err := a.validate()
 	if err != nil {
-		return m, err
+		return dst, err
 	}
 
-	return
+	start := len(dst)
+	dst, err = appendAddress(dst, a.SourceAddr, unixPathLength)
+	if err != nil {
+		return dst[:start], err
+	}
+
+	dst, err = appendAddress(dst, a.DestinationAddr, unixPathLength)
+	if err != nil {
+		return dst[:start], err
+	}
+
+	return dst, nil
+}
+
+// validate checks that both paths fit in the fixed-size fields, since
+// longer paths would have been silently truncated.
+func (a UnixAddr) validate() error {
+	if a.SourceAddr == nil || a.DestinationAddr == nil {
+		return fmt.Errorf("expected all addresses to present, got source %v and destination %v", a.SourceAddr, a.DestinationAddr)
+	}
+
+	if len(a.SourceAddr.Name) > unixPathLength {
+		return fmt.Errorf("source address path is %d bytes long, but at most %d bytes are allowed", len(a.SourceAddr.Name), unixPathLength)
+	}
+
+	if len(a.DestinationAddr.Name) > unixPathLength {
+		return fmt.Errorf("destination address path is %d bytes long, but at most %d bytes are allowed", len(a.DestinationAddr.Name), unixPathLength)
+	}
+
+	return nil
 }
 
 func (a UnixAddr) getLength() AddressLength {
-	return 216
+	return unixAddressLength
+}
+
+func (a UnixAddr) getSignature() (ProtocolByte, error) {
+	if a.SourceAddr == nil || a.DestinationAddr == nil {
+		return ProtocolByte{AddressFamilyUNIX, TransportProtocolUNSPEC},
+			fmt.Errorf("expected all addresses to present, got source %v and destination %v", a.SourceAddr, a.DestinationAddr)
+	}
+
+	transport, err := getPairTransportProtocol(a.SourceAddr, a.DestinationAddr)
+	return ProtocolByte{AddressFamilyUNIX, transport}, err
 }
 
-func (a UnixAddr) getSignature() ProtocolByte {
-	return ProtocolByte{AddressFamilyUNIX, getTransportProtocol(a.SourceAddr)}
+func (a UnixAddr) getAddresses() (net.Addr, net.Addr) {
+	return a.SourceAddr, a.DestinationAddr
 }
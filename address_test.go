@@ -0,0 +1,557 @@
+package haproxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapAddress(t *testing.T) {
+	tests := []struct {
+		src, dst net.Addr
+		expected ProxyAddress
+	}{
+		{ // Plain IPv4
+			&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 42446},
+			&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1338},
+			&IPv4Address{},
+		},
+		{ // IPv4-mapped IPv6
+			&net.TCPAddr{IP: net.ParseIP("::ffff:127.0.0.1"), Port: 42446},
+			&net.TCPAddr{IP: net.ParseIP("::ffff:127.0.0.1"), Port: 1338},
+			&IPv4Address{},
+		},
+		{ // No port
+			&net.UDPAddr{IP: net.ParseIP("127.0.0.1")},
+			&net.UDPAddr{IP: net.ParseIP("127.0.0.1")},
+			&IPv4Address{},
+		},
+		{ // IPv6
+			&net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 42446},
+			&net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::5"), Port: 1338},
+			&IPv6Address{},
+		},
+		{ // IPv6 with zone
+			&net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 42446, Zone: "eth0"},
+			&net.TCPAddr{IP: net.ParseIP("fe80::2"), Port: 1338, Zone: "eth0"},
+			&IPv6Address{},
+		},
+		{ // UNIX
+			&net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+			&net.UnixAddr{Name: "/tmp/destination.sock", Net: "unix"},
+			&UnixAddr{},
+		},
+	}
+
+	for _, test := range tests {
+		address, err := WrapAddress(test.src, test.dst)
+		assert.Nil(t, err, test.src.String())
+		assert.IsType(t, test.expected, address, test.src.String())
+
+		source, destination := address.getAddresses()
+		assert.Equal(t, test.src, source)
+		assert.Equal(t, test.dst, destination)
+	}
+}
+
+func TestWrapAddress_Invalid(t *testing.T) {
+	tests := [][2]net.Addr{
+		{ // Missing IP
+			&net.TCPAddr{Port: 42446},
+			&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1338},
+		},
+		{ // Mixed families
+			&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 42446},
+			&net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 1338},
+		},
+		{ // Mixed types
+			&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 42446},
+			&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1338},
+		},
+		{ // Missing source
+			nil,
+			&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1338},
+		},
+		{ // Unsupported type
+			customAddr{},
+			customAddr{},
+		},
+	}
+
+	for _, test := range tests {
+		_, err := WrapAddress(test[0], test[1])
+		assert.NotNil(t, err)
+	}
+}
+
+func TestIPv6Address_Zone(t *testing.T) {
+	zoned := &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &IPv6Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 42446, Zone: "eth0"},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("fe80::2"), Port: 1338, Zone: "2"},
+		},
+	}
+
+	plain := &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &IPv6Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 42446},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("fe80::2"), Port: 1338},
+		},
+	}
+
+	// Zone is dropped when writing
+	encoded := MustEncode(zoned)
+	assert.Equal(t, MustEncode(plain), encoded)
+
+	var header Header
+	_, err := header.ReadFrom(bytes.NewReader(encoded))
+	assert.Nil(t, err)
+
+	source, _ := header.ProxyAddress.getAddresses()
+	assert.Equal(t, "", source.(*net.TCPAddr).Zone)
+	assert.True(t, net.ParseIP("fe80::1").Equal(source.(*net.TCPAddr).IP))
+}
+
+func TestUnixAddr_RoundTrip(t *testing.T) {
+	header := &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/tmp/a.sock", Net: "unixgram"},
+			DestinationAddr: &net.UnixAddr{Name: "/var/run/backend.sock", Net: "unixgram"},
+		},
+	}
+
+	encoded := MustEncode(header)
+	assert.Equal(t, 16+216, len(encoded))
+
+	var decoded Header
+	_, err := decoded.ReadFrom(bytes.NewReader(encoded))
+	assert.Nil(t, err)
+
+	addr := decoded.ProxyAddress.(*UnixAddr)
+	assert.Equal(t, "/tmp/a.sock", addr.SourceAddr.Name)
+	assert.Equal(t, "/var/run/backend.sock", addr.DestinationAddr.Name)
+	assert.Nil(t, header.SelfConsistent())
+}
+
+func TestUnixAddr_PathTooLong(t *testing.T) {
+	long := &net.UnixAddr{Name: "/tmp/" + strings.Repeat("a", 104), Net: "unix"}
+	short := &net.UnixAddr{Name: "/tmp/a.sock", Net: "unix"}
+
+	_, err := WrapAddress(short, long)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "destination")
+	assert.Contains(t, err.Error(), "109")
+
+	buffer := &bytes.Buffer{}
+	_, err = UnixAddr{SourceAddr: long, DestinationAddr: short}.WriteTo(buffer)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "source")
+	assert.Equal(t, 0, buffer.Len())
+
+	// Path that takes the whole field is fine
+	exact := &net.UnixAddr{Name: "/tmp/" + strings.Repeat("a", 103), Net: "unix"}
+	_, err = WrapAddress(exact, exact)
+	assert.Nil(t, err)
+}
+
+type customAddr struct{}
+
+func (customAddr) Network() string { return "custom" }
+func (customAddr) String() string  { return "custom" }
+
+func TestHeader_WriteTo_UnsupportedAddress(t *testing.T) {
+	header := Header{
+		Command:      CommandPROXY,
+		ProxyAddress: &IPv4Address{SourceAddr: customAddr{}, DestinationAddr: customAddr{}},
+	}
+
+	buffer := &bytes.Buffer{}
+	n, err := header.WriteTo(buffer)
+	assert.IsType(t, &UnsupportedAddressError{}, err)
+	assert.Equal(t, 0, int(n))
+	assert.Equal(t, 0, buffer.Len())
+
+	_, err = header.WriteToV1(buffer)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, buffer.Len())
+
+	_, err = IPv6Address{SourceAddr: customAddr{}, DestinationAddr: customAddr{}}.WriteTo(buffer)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestAddressFamily_String(t *testing.T) {
+	assert.Equal(t, "UNSPEC", AddressFamilyUNSPEC.String())
+	assert.Equal(t, "INET6", AddressFamilyINET6.String())
+	assert.Equal(t, "UNKNOWN(0x05)", AddressFamily(0x05).String())
+}
+
+func TestTransportProtocol_String(t *testing.T) {
+	assert.Equal(t, "UNSPEC", TransportProtocolUNSPEC.String())
+	assert.Equal(t, "DGRAM", TransportProtocolDGRAM.String())
+	assert.Equal(t, "UNKNOWN(0x0f)", TransportProtocol(0x0f).String())
+}
+
+func TestProxyAddress_getLength(t *testing.T) {
+	addresses := []ProxyAddress{
+		&IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 42446},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1338},
+		},
+		&IPv4Address{
+			SourceAddr:      &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1).To4(), Port: 42446},
+			DestinationAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1).To4(), Port: 1338},
+		},
+		&IPv6Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 42446},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("::1"), Port: 1338},
+		},
+		&IPv6Address{
+			SourceAddr:      &net.UDPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 42446},
+			DestinationAddr: &net.UDPAddr{IP: net.ParseIP("::1"), Port: 1338},
+		},
+		&UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+			DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unix"},
+		},
+		&UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unixgram"},
+			DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unixgram"},
+		},
+	}
+
+	for _, address := range addresses {
+		buffer := &bytes.Buffer{}
+		n, err := address.WriteTo(buffer)
+		assert.Nil(t, err)
+		assert.Equal(t, int(address.getLength()), int(n))
+
+		for _, tlvs := range [][]TLV{nil, {{TLVTypeALPN, []byte("h2")}, {TLVTypeNOOP, nil}}} {
+			header := Header{Command: CommandPROXY, ProxyAddress: address, TLVs: tlvs}
+			data := MustEncode(&header)
+
+			// Everything after the fixed 16 bytes must be covered by the declared length
+			declared := int(data[14])<<8 | int(data[15])
+			assert.Equal(t, len(data)-16, declared)
+		}
+	}
+}
+
+func TestUnixAddr_RoundTrip_Stream(t *testing.T) {
+	listener, err := net.Listen("unix", filepath.Join(t.TempDir(), "backend.sock"))
+	if err != nil {
+		t.Skip("UNIX sockets are not available:", err)
+	}
+	defer listener.Close()
+
+	header := &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/tmp/client.sock", Net: "unix"},
+			DestinationAddr: listener.Addr().(*net.UnixAddr),
+		},
+	}
+
+	var decoded Header
+	_, err = decoded.ReadFrom(bytes.NewReader(MustEncode(header)))
+	assert.Nil(t, err)
+
+	_, destination := decoded.ProxyAddress.getAddresses()
+	assert.Equal(t, listener.Addr(), destination)
+
+	// Decoded address must be usable with the net package as is
+	conn, err := net.DialUnix(destination.Network(), nil, destination.(*net.UnixAddr))
+	assert.Nil(t, err)
+	if conn != nil {
+		_ = conn.Close()
+	}
+}
+
+func TestIPv4Address_WriteTo_MalformedIP(t *testing.T) {
+	addresses := []ProxyAddress{
+		&IPv4Address{
+			SourceAddr:      &net.TCPAddr{Port: 42446},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1338},
+		},
+		&IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 42446},
+			DestinationAddr: &net.TCPAddr{IP: net.IP{127, 0, 1}, Port: 1338},
+		},
+		&IPv6Address{
+			SourceAddr:      &net.UDPAddr{Port: 42446},
+			DestinationAddr: &net.UDPAddr{IP: net.ParseIP("::1"), Port: 1338},
+		},
+	}
+
+	for _, address := range addresses {
+		buffer := &bytes.Buffer{}
+		n, err := address.WriteTo(buffer)
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, int(n))
+		assert.Equal(t, 0, buffer.Len())
+
+		_, err = Header{Command: CommandPROXY, ProxyAddress: address}.WriteTo(buffer)
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, buffer.Len())
+	}
+}
+
+func TestIPv4Address_WriteTo_Representations(t *testing.T) {
+	expected := []byte{127, 0, 0, 1, 192, 168, 0, 11, 0xa5, 0xce, 0x05, 0x3a}
+	ips := [][2]net.IP{
+		{net.IP{127, 0, 0, 1}, net.IP{192, 168, 0, 11}},
+		{net.ParseIP("127.0.0.1"), net.ParseIP("192.168.0.11")},
+		{net.IP{127, 0, 0, 1}, net.ParseIP("::ffff:192.168.0.11")},
+	}
+
+	for _, pair := range ips {
+		address := IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: pair[0], Port: 42446},
+			DestinationAddr: &net.TCPAddr{IP: pair[1], Port: 1338},
+		}
+
+		buffer := &bytes.Buffer{}
+		_, err := address.WriteTo(buffer)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, buffer.Bytes())
+	}
+
+	// IPv6 address mislabeled as IPv4
+	address := IPv4Address{
+		SourceAddr:      &net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 42446},
+		DestinationAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1338},
+	}
+
+	buffer := &bytes.Buffer{}
+	_, err := address.WriteTo(buffer)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestProtocolByte_ReadFrom(t *testing.T) {
+	readers := []io.Reader{
+		bufio.NewReader(bytes.NewReader([]byte{0x21})),
+		iotest.OneByteReader(bytes.NewReader([]byte{0x21})),
+	}
+
+	for _, r := range readers {
+		var protocol ProtocolByte
+		n, err := protocol.ReadFrom(r)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), n)
+		assert.Equal(t, ProtocolByte{AddressFamilyINET6, TransportProtocolSTREAM}, protocol)
+
+		n, err = protocol.ReadFrom(r)
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, int64(0), n)
+	}
+}
+
+func BenchmarkProtocolByte_ReadFrom(b *testing.B) {
+	b.ReportAllocs()
+	data := bytes.Repeat([]byte{0x11}, 4096)
+	reader := bufio.NewReader(nil)
+	var protocol ProtocolByte
+	for i := 0; i < b.N; i++ {
+		if i%len(data) == 0 {
+			reader.Reset(bytes.NewReader(data))
+		}
+
+		_, err := protocol.ReadFrom(reader)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestProxyAddress_MixedTransportProtocols(t *testing.T) {
+	addresses := []ProxyAddress{
+		&IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+			DestinationAddr: &net.UDPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+		},
+		&IPv6Address{
+			SourceAddr:      &net.UDPAddr{IP: net.ParseIP("2345:425:2ca1::567:5673:23b5"), Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443},
+		},
+		&UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+			DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unixgram"},
+		},
+	}
+
+	for _, address := range addresses {
+		_, err := address.getSignature()
+		assert.NotNil(t, err)
+
+		buffer := &bytes.Buffer{}
+		_, err = Header{Command: CommandPROXY, ProxyAddress: address}.WriteTo(buffer)
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, buffer.Len())
+	}
+}
+
+func TestProxyAddress_MixedAddressFamilies(t *testing.T) {
+	address := &IPv4Address{
+		SourceAddr:      &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+		DestinationAddr: &net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443},
+	}
+
+	buffer := &bytes.Buffer{}
+	_, err := Header{Command: CommandPROXY, ProxyAddress: address}.WriteTo(buffer)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestUnixAddr_DeclaredLength(t *testing.T) {
+	address := &UnixAddr{
+		SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+		DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unix"},
+	}
+
+	written, err := address.WriteTo(&bytes.Buffer{})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(address.getLength()), written)
+
+	tlvSets := [][]TLV{
+		nil,
+		{{TLVTypeALPN, []byte("h2")}},
+		{{TLVTypeAUTHORITY, []byte("example.com")}, {TLVTypeNOOP, make([]byte, 300)}},
+	}
+
+	for _, tlvs := range tlvSets {
+		header := Header{Command: CommandPROXY, ProxyAddress: address, TLVs: tlvs}
+		for _, opts := range []WriteOptions{{}, {Checksum: true}} {
+			buffer := &bytes.Buffer{}
+			n, err := header.WriteToWithOptions(buffer, opts)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(buffer.Len()), n)
+
+			// Length field covers exactly the bytes that follow it
+			declared := binary.BigEndian.Uint16(buffer.Bytes()[14:16])
+			assert.Equal(t, buffer.Len()-16, int(declared))
+		}
+	}
+}
+
+func TestValidateProtocolByte(t *testing.T) {
+	families := []AddressFamily{AddressFamilyUNSPEC, AddressFamilyINET, AddressFamilyINET6, AddressFamilyUNIX}
+	transports := []TransportProtocol{TransportProtocolUNSPEC, TransportProtocolSTREAM, TransportProtocolDGRAM}
+
+	for _, family := range families {
+		for _, transport := range transports {
+			assert.Nil(t, ValidateProtocolByte(ProtocolByte{family, transport}))
+		}
+	}
+
+	invalid := []ProtocolByte{
+		{AddressFamily(0x4), TransportProtocolSTREAM},
+		{AddressFamily(0xF), TransportProtocolUNSPEC},
+		{AddressFamilyINET, TransportProtocol(0x3)},
+		{AddressFamilyUNIX, TransportProtocol(0xF)},
+	}
+
+	for _, protocol := range invalid {
+		err := ValidateProtocolByte(protocol)
+		assert.NotNil(t, err, protocol)
+
+		// Receivers reject them with the same error
+		data := append(Signature(), 0x21, byte(protocol.AddressFamily<<4)|byte(protocol.TransportProtocol), 0x00, 0x00)
+		var header Header
+		_, readErr := header.ReadFrom(bytes.NewReader(data))
+		assert.Equal(t, err, readErr)
+	}
+}
+
+func TestIPv6Address_MappedIPv4(t *testing.T) {
+	header := &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &IPv6Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("::ffff:192.168.0.1"), Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443},
+		},
+	}
+	data := MustEncode(header)
+
+	var decoded Header
+	assert.Nil(t, decoded.UnmarshalBinary(data))
+	assert.IsType(t, &IPv6Address{}, decoded.ProxyAddress)
+
+	source, destination := decoded.ProxyAddress.getAddresses()
+	assert.Equal(t, net.IPv4(192, 168, 0, 1).To4(), source.(*net.TCPAddr).IP)
+	assert.Equal(t, net.ParseIP("2607:f0d0:1002:51::4"), destination.(*net.TCPAddr).IP)
+
+	// Mapped form is restored when writing
+	assert.Equal(t, data, MustEncode(&decoded))
+
+	var v1 Header
+	assert.Nil(t, v1.UnmarshalText([]byte("PROXY TCP6 ::ffff:192.168.0.1 2607:f0d0:1002:51::4 56324 443\r\n")))
+	source, _ = v1.ProxyAddress.getAddresses()
+	assert.Equal(t, net.IPv4(192, 168, 0, 1).To4(), source.(*net.TCPAddr).IP)
+
+	text, err := v1.MarshalText()
+	assert.Nil(t, err)
+	assert.Equal(t, "PROXY TCP6 ::ffff:192.168.0.1 2607:f0d0:1002:51::4 56324 443\r\n", string(text))
+}
+
+func TestGetPort(t *testing.T) {
+	port, err := getPort(&net.UDPAddr{IP: net.ParseIP("192.168.0.1"), Port: 65535})
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(65535), port)
+
+	port, err = getPort(&net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 443})
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(443), port)
+
+	invalid := []net.Addr{
+		&net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+		(*net.TCPAddr)(nil),
+		(*net.UDPAddr)(nil),
+		&net.UDPAddr{IP: net.ParseIP("192.168.0.1"), Port: 65536},
+		&net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: -1},
+		nil,
+	}
+
+	for _, addr := range invalid {
+		_, err := getPort(addr)
+		assert.NotNil(t, err, addr)
+	}
+}
+
+func TestIPv4Address_DatagramPorts(t *testing.T) {
+	for _, ports := range [][2]int{{0, 0}, {1, 65535}, {56324, 443}} {
+		header := &Header{Command: CommandPROXY, ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.UDPAddr{IP: net.IPv4(192, 168, 0, 1).To4(), Port: ports[0]},
+			DestinationAddr: &net.UDPAddr{IP: net.IPv4(192, 168, 0, 11).To4(), Port: ports[1]},
+		}}
+
+		var decoded Header
+		assert.Nil(t, decoded.UnmarshalBinary(MustEncode(header)))
+		assert.Equal(t, header, &decoded)
+	}
+}
+
+func TestProxyAddress_NilAddresses(t *testing.T) {
+	addresses := []ProxyAddress{
+		&IPv4Address{SourceAddr: (*net.UDPAddr)(nil), DestinationAddr: (*net.UDPAddr)(nil)},
+		&IPv6Address{SourceAddr: (*net.TCPAddr)(nil), DestinationAddr: &net.TCPAddr{IP: net.ParseIP("::1"), Port: 443}},
+		&UnixAddr{SourceAddr: &net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"}},
+		&IPv4Address{SourceAddr: (*net.UnixAddr)(nil), DestinationAddr: (*net.UnixAddr)(nil)},
+	}
+
+	for _, address := range addresses {
+		_, err := Header{Command: CommandPROXY, ProxyAddress: address}.WriteTo(&bytes.Buffer{})
+		assert.NotNil(t, err)
+	}
+}
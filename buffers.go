@@ -0,0 +1,49 @@
+package haproxy
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// Buffers returns the serialized header split into buffers that can be sent
+// with a single writev call using net.Buffers.WriteTo. Fixed part of the
+// header and addresses are serialized into the first buffer, while TLV
+// values are referenced directly, so the header must not be modified until
+// the buffers are written.
+func (h Header) Buffers() (net.Buffers, error) {
+	prefix, err := Header{Command: h.Command, ProxyAddress: h.ProxyAddress}.AppendTo(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	buffers := net.Buffers{prefix}
+	if h.Command != CommandPROXY {
+		return buffers, nil
+	}
+
+	// Length field has to cover TLVs which are not in the first buffer
+	length, err := h.bodyLength()
+	if err != nil {
+		return nil, err
+	}
+
+	binary.BigEndian.PutUint16(buffers[0][14:16], uint16(length))
+
+	for _, tlv := range h.TLVs {
+		buffers = append(buffers, []byte{byte(tlv.Type), byte(len(tlv.Value) >> 8), byte(len(tlv.Value))}, tlv.Value)
+	}
+
+	return buffers, nil
+}
+
+// BuffersWithPayload is like Buffers, but also appends payload as the last
+// buffer, so that a proxy can send the header together with the first chunk
+// of data in a single call. Like TLV values, payload is not copied.
+func (h Header) BuffersWithPayload(payload []byte) (net.Buffers, error) {
+	buffers, err := h.Buffers()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(buffers, payload), nil
+}
@@ -0,0 +1,37 @@
+package haproxy
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader_BuffersWithPayload(t *testing.T) {
+	header := &Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[1].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeALPN, []byte("h2")}, {TLVTypeAUTHORITY, []byte("example.com")}},
+	}
+
+	buffers, err := header.BuffersWithPayload([]byte("hello"))
+	assert.Nil(t, err)
+	assert.Len(t, buffers, 6)
+
+	output := &bytes.Buffer{}
+	_, err = buffers.WriteTo(output)
+	assert.Nil(t, err)
+	assert.Equal(t, append(MustEncode(header), "hello"...), output.Bytes())
+}
+
+func TestHeader_Buffers_Local(t *testing.T) {
+	header := &Header{Command: CommandLOCAL, TLVs: []TLV{{TLVTypeALPN, []byte("h2")}}}
+
+	buffers, err := header.Buffers()
+	assert.Nil(t, err)
+
+	output := &bytes.Buffer{}
+	_, err = buffers.WriteTo(output)
+	assert.Nil(t, err)
+	assert.Equal(t, MustEncode(header), output.Bytes())
+}
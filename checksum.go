@@ -0,0 +1,85 @@
+package haproxy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// CRC32C returns the checksum transmitted in TLVTypeCRC32C TLV. It returns
+// false if there is no such TLV or its value is not 4 bytes long.
+func (h Header) CRC32C() (uint32, bool) {
+	tlv, ok := h.findTLV(TLVTypeCRC32C)
+	if !ok || len(tlv.Value) != 4 {
+		return 0, false
+	}
+
+	return binary.BigEndian.Uint32(tlv.Value), true
+}
+
+// verifyChecksum checks the checksum TLV, if present, against raw header
+// bytes the header was decoded from.
+func (h Header) verifyChecksum(raw []byte) error {
+	// Checksum TLV is located after the fixed 16-byte part, addresses and all
+	// preceding TLVs
+	offset := 16
+	if h.ProxyAddress != nil {
+		offset += int(h.ProxyAddress.getLength())
+	}
+
+	for _, tlv := range h.TLVs {
+		offset += 3
+		if tlv.Type != TLVTypeCRC32C {
+			offset += len(tlv.Value)
+			continue
+		}
+
+		if len(tlv.Value) != 4 {
+			return fmt.Errorf("malformed CRC32C TLV: expected 4 bytes, but got %d", len(tlv.Value))
+		}
+
+		transmitted := binary.BigEndian.Uint32(tlv.Value)
+		computed := checksum(raw, offset)
+		if transmitted != computed {
+			return &ChecksumError{Transmitted: transmitted, Computed: computed}
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// checksum computes CRC32c of data, treating 4 bytes at offset as zeroes.
+func checksum(data []byte, offset int) uint32 {
+	crc := crc32.Update(0, castagnoliTable, data[:offset])
+	crc = crc32.Update(crc, castagnoliTable, []byte{0, 0, 0, 0})
+	return crc32.Update(crc, castagnoliTable, data[offset+4:])
+}
+
+// appendWithChecksum appends the header with checksum TLV. Since the checksum
+// covers the whole header, it is serialized with zero checksum first, and the
+// computed value is patched in afterwards.
+func (h Header) appendWithChecksum(dst []byte, opts WriteOptions) ([]byte, error) {
+	tlvs := make([]TLV, 0, len(h.TLVs)+1)
+	for _, tlv := range h.TLVs {
+		if tlv.Type != TLVTypeCRC32C {
+			tlvs = append(tlvs, tlv)
+		}
+	}
+
+	h.TLVs = append(tlvs, TLV{Type: TLVTypeCRC32C, Value: make([]byte, 4)})
+	opts.Checksum = false
+
+	start := len(dst)
+	dst, err := h.appendWithOptions(dst, opts)
+	if err != nil {
+		return dst, err
+	}
+
+	// Checksum TLV is the last one, so its value occupies the last 4 bytes
+	binary.BigEndian.PutUint32(dst[len(dst)-4:], crc32.Checksum(dst[start:], castagnoliTable))
+	return dst, nil
+}
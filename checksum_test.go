@@ -0,0 +1,74 @@
+package haproxy
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var checksumHeader = []byte{
+	0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x0d, 0x0a, 0x51, 0x55, 0x49, 0x54, 0x0a, 0x21, 0x11, 0x00, 0x13,
+	0x7f, 0x00, 0x00, 0x01, 0x7f, 0x00, 0x00, 0x01, 0xa5, 0xce, 0x05, 0x3a, 0x03, 0x00, 0x04, 0xef,
+	0x09, 0x12, 0xf5,
+}
+
+func TestHeader_ReadFromWithOptions_VerifyChecksum(t *testing.T) {
+	var header Header
+	n, err := header.ReadFromWithOptions(bytes.NewReader(checksumHeader), ReadOptions{VerifyChecksum: true})
+	assert.Nil(t, err)
+	assert.Equal(t, len(checksumHeader), int(n))
+
+	crc, ok := header.CRC32C()
+	assert.True(t, ok)
+	assert.Equal(t, uint32(0xef0912f5), crc)
+}
+
+func TestHeader_ReadFromWithOptions_ChecksumMismatch(t *testing.T) {
+	data := append([]byte{}, checksumHeader...)
+	data[16] = 0x0a // Tamper with the source address
+
+	var header Header
+	_, err := header.ReadFromWithOptions(bytes.NewReader(data), ReadOptions{VerifyChecksum: true})
+	assert.IsType(t, &ChecksumError{}, err)
+	assert.Equal(t, uint32(0xef0912f5), err.(*ChecksumError).Transmitted)
+
+	// Verification is disabled by default
+	_, err = header.ReadFrom(bytes.NewReader(data))
+	assert.Nil(t, err)
+}
+
+func TestHeader_CRC32C_Missing(t *testing.T) {
+	_, ok := Header{}.CRC32C()
+	assert.False(t, ok)
+}
+
+func TestHeader_WriteToWithOptions_Checksum(t *testing.T) {
+	header := *headers[0]
+	header.TLVs = []TLV{{TLVTypeCRC32C, []byte{1, 2, 3, 4}}}
+
+	buffer := &bytes.Buffer{}
+	n, err := header.WriteToWithOptions(buffer, WriteOptions{Checksum: true})
+	assert.Nil(t, err)
+	assert.Equal(t, len(checksumHeader), int(n))
+	assert.Equal(t, checksumHeader, buffer.Bytes())
+
+	// Original header must not be modified
+	assert.Equal(t, []TLV{{TLVTypeCRC32C, []byte{1, 2, 3, 4}}}, header.TLVs)
+
+	var decoded Header
+	_, err = decoded.ReadFromWithOptions(buffer, ReadOptions{VerifyChecksum: true})
+	assert.Nil(t, err)
+}
+
+func BenchmarkHeader_WriteToWithOptions_Checksum(b *testing.B) {
+	b.ReportAllocs()
+	opts := WriteOptions{Checksum: true}
+	for i := 0; i < b.N; i++ {
+		_, err := headers[0].WriteToWithOptions(io.Discard, opts)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package haproxy
+
+import "net"
+
+// Clone returns a deep copy of the header, so that addresses and TLV values
+// of the copy can be modified without affecting the original, e.g. when a
+// template header is reused for many connections. Addresses of types unknown
+// to this package are shared with the original.
+func (h Header) Clone() *Header {
+	clone := &Header{
+		Command:      h.Command,
+		ProxyAddress: cloneProxyAddress(h.ProxyAddress),
+		Unsupported:  h.Unsupported,
+	}
+
+	if h.TLVs != nil {
+		clone.TLVs = make([]TLV, len(h.TLVs))
+		for i, tlv := range h.TLVs {
+			clone.TLVs[i] = TLV{Type: tlv.Type, Value: cloneBytes(tlv.Value)}
+		}
+	}
+
+	return clone
+}
+
+func cloneProxyAddress(a ProxyAddress) ProxyAddress {
+	switch a := a.(type) {
+	case *IPv4Address:
+		if a == nil {
+			return a
+		}
+
+		return &IPv4Address{SourceAddr: cloneAddr(a.SourceAddr), DestinationAddr: cloneAddr(a.DestinationAddr)}
+	case *IPv6Address:
+		if a == nil {
+			return a
+		}
+
+		return &IPv6Address{SourceAddr: cloneAddr(a.SourceAddr), DestinationAddr: cloneAddr(a.DestinationAddr)}
+	case *UnixAddr:
+		if a == nil {
+			return a
+		}
+
+		return &UnixAddr{SourceAddr: cloneUnixAddr(a.SourceAddr), DestinationAddr: cloneUnixAddr(a.DestinationAddr)}
+	default:
+		return a
+	}
+}
+
+func cloneAddr(a net.Addr) net.Addr {
+	switch a := a.(type) {
+	case *net.TCPAddr:
+		if a == nil {
+			return a
+		}
+
+		return &net.TCPAddr{IP: net.IP(cloneBytes(a.IP)), Port: a.Port, Zone: a.Zone}
+	case *net.UDPAddr:
+		if a == nil {
+			return a
+		}
+
+		return &net.UDPAddr{IP: net.IP(cloneBytes(a.IP)), Port: a.Port, Zone: a.Zone}
+	case *net.UnixAddr:
+		return cloneUnixAddr(a)
+	default:
+		return a
+	}
+}
+
+func cloneUnixAddr(a *net.UnixAddr) *net.UnixAddr {
+	if a == nil {
+		return nil
+	}
+
+	return &net.UnixAddr{Name: a.Name, Net: a.Net}
+}
+
+func cloneBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append(make([]byte, 0, len(b)), b...)
+}
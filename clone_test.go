@@ -0,0 +1,62 @@
+package haproxy
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader_Clone(t *testing.T) {
+	for _, header := range headers {
+		clone := header.Clone()
+		assert.Equal(t, header, clone)
+		assert.Equal(t, MustEncode(header), MustEncode(clone))
+	}
+}
+
+func TestHeader_Clone_TLVs(t *testing.T) {
+	header := &Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[0].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeUNIQUEID, []byte("request-42")}, {TLVTypeALPN, []byte("h2")}},
+	}
+
+	clone := header.Clone()
+	clone.TLVs[0].Value[0] = 'R'
+	clone.TLVs[1].Type = TLVTypeAUTHORITY
+	assert.Nil(t, clone.SetNetNS("tenant-a"))
+
+	assert.Equal(t, []TLV{{TLVTypeUNIQUEID, []byte("request-42")}, {TLVTypeALPN, []byte("h2")}}, header.TLVs)
+	assert.Len(t, clone.TLVs, 3)
+}
+
+func TestHeader_Clone_Addresses(t *testing.T) {
+	header := &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+		},
+	}
+
+	clone := header.Clone()
+	source, _ := clone.ProxyAddress.getAddresses()
+	source.(*net.TCPAddr).IP[15] = 2
+	source.(*net.TCPAddr).Port = 1
+
+	source, _ = header.ProxyAddress.getAddresses()
+	assert.Equal(t, &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324}, source)
+
+	unix := &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+			DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unix"},
+		},
+	}
+
+	clone = unix.Clone()
+	clone.ProxyAddress.(*UnixAddr).SourceAddr.Name = "/tmp/other.sock"
+	assert.Equal(t, "/tmp/source.sock", unix.ProxyAddress.(*UnixAddr).SourceAddr.Name)
+}
@@ -0,0 +1,58 @@
+package haproxy
+
+import "encoding/binary"
+
+const (
+	// TLVTypeAWS is used by AWS Network Load Balancers with PrivateLink. The
+	// first byte of its value is one of AWSSubtype values, and the rest
+	// depends on it.
+	TLVTypeAWS TLVType = 0xEA
+
+	// TLVTypeAZURE is used by Azure Private Link Service. The first byte of
+	// its value is one of AzureSubtype values, and the rest depends on it.
+	TLVTypeAZURE TLVType = 0xEE
+)
+
+// AWSSubtype is the first byte of TLVTypeAWS TLV value.
+type AWSSubtype byte
+
+const (
+	// AWSSubtypeVPCEID is followed by the US-ASCII string ID of the VPC
+	// endpoint the connection came through, e.g. "vpce-08d2bf15fac5001c9".
+	AWSSubtypeVPCEID AWSSubtype = 0x01
+)
+
+// AWSVPCEndpointID returns the ID of the VPC endpoint the connection came
+// through from TLVTypeAWS TLV of AWSSubtypeVPCEID subtype. TLVs of other
+// subtypes are skipped, and remain available in Header.TLVs.
+func (h Header) AWSVPCEndpointID() (string, bool) {
+	for _, tlv := range h.TLVs {
+		if tlv.Type == TLVTypeAWS && len(tlv.Value) > 0 && AWSSubtype(tlv.Value[0]) == AWSSubtypeVPCEID {
+			return string(tlv.Value[1:]), true
+		}
+	}
+
+	return "", false
+}
+
+// AzureSubtype is the first byte of TLVTypeAZURE TLV value.
+type AzureSubtype byte
+
+const (
+	// AzureSubtypePRIVATEENDPOINTLINKID is followed by the 4-byte LINKID of
+	// the private endpoint the connection came through. Unlike the rest of
+	// the protocol, it is encoded in little-endian byte order.
+	AzureSubtypePRIVATEENDPOINTLINKID AzureSubtype = 0x01
+)
+
+// AzureLinkID returns the LINKID of the private endpoint the connection came
+// through from TLVTypeAZURE TLV of AzureSubtypePRIVATEENDPOINTLINKID subtype.
+func (h Header) AzureLinkID() (uint32, bool) {
+	for _, tlv := range h.TLVs {
+		if tlv.Type == TLVTypeAZURE && len(tlv.Value) == 5 && AzureSubtype(tlv.Value[0]) == AzureSubtypePRIVATEENDPOINTLINKID {
+			return binary.LittleEndian.Uint32(tlv.Value[1:]), true
+		}
+	}
+
+	return 0, false
+}
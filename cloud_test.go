@@ -0,0 +1,42 @@
+package haproxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader_AWSVPCEndpointID(t *testing.T) {
+	header := Header{TLVs: []TLV{
+		{TLVTypeAWS, []byte{0x02, 0x42}}, // Unknown subtype
+		{TLVTypeAWS, append([]byte{0x01}, "vpce-08d2bf15fac5001c9"...)},
+	}}
+
+	id, ok := header.AWSVPCEndpointID()
+	assert.True(t, ok)
+	assert.Equal(t, "vpce-08d2bf15fac5001c9", id)
+
+	header.TLVs = header.TLVs[:1]
+	_, ok = header.AWSVPCEndpointID()
+	assert.False(t, ok)
+
+	header.TLVs = []TLV{{TLVTypeAWS, nil}}
+	_, ok = header.AWSVPCEndpointID()
+	assert.False(t, ok)
+}
+
+func TestHeader_AzureLinkID(t *testing.T) {
+	header := Header{TLVs: []TLV{{TLVTypeAZURE, []byte{0x01, 0x78, 0x56, 0x34, 0x12}}}}
+
+	id, ok := header.AzureLinkID()
+	assert.True(t, ok)
+	assert.Equal(t, uint32(0x12345678), id)
+
+	header.TLVs = []TLV{{TLVTypeAZURE, []byte{0x01, 0x78, 0x56}}}
+	_, ok = header.AzureLinkID()
+	assert.False(t, ok)
+
+	header.TLVs = []TLV{{TLVTypeAZURE, []byte{0x02, 0x78, 0x56, 0x34, 0x12}}}
+	_, ok = header.AzureLinkID()
+	assert.False(t, ok)
+}
@@ -0,0 +1,209 @@
+package haproxy
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Conn wraps a net.Conn that starts with a PROXY header. The header is read
+// lazily on the first call to Read, RemoteAddr or LocalAddr, or eagerly by
+// calling Header. After that, RemoteAddr and LocalAddr report addresses from
+// the header, and Read returns data that follows it. All other methods are
+// delegated to the underlying connection.
+type Conn struct {
+	net.Conn
+
+	// requireHeader is set to false to pass connections without header through
+	requireHeader bool
+
+	// headerReadTimeout bounds the time spent reading the header, if non-zero
+	headerReadTimeout time.Duration
+
+	// untrusted is set if the header must not be honored, since the peer is
+	// not a trusted proxy
+	untrusted bool
+
+	// deadlineMu guards the read deadline set by the caller and the one that
+	// bounds reading the header, so that the earlier of them is applied
+	deadlineMu     sync.Mutex
+	readDeadline   time.Time
+	headerDeadline time.Time
+
+	once   sync.Once
+	reader io.Reader
+	header *Header
+	err    error
+}
+
+// NewConn wraps conn into a Conn that requires a header to be present.
+func NewConn(conn net.Conn) *Conn {
+	return &Conn{Conn: conn, requireHeader: true}
+}
+
+// Header reads the header if it was not read yet, and returns it. If the
+// connection does not start with a valid header, it is closed and the error
+// is returned by this and all subsequent calls to Header and Read.
+//
+// A nil header with nil error is returned for connections that were passed
+// through by a Listener that does not require a header.
+func (c *Conn) Header() (*Header, error) {
+	c.once.Do(c.readHeader)
+	return c.header, c.err
+}
+
+func (c *Conn) readHeader() {
+	if c.untrusted && c.requireHeader {
+		c.err = ErrUntrustedProxy
+		_ = c.Conn.Close()
+		return
+	}
+
+	if c.headerReadTimeout > 0 {
+		c.err = c.setHeaderDeadline(time.Now().Add(c.headerReadTimeout))
+		if c.err != nil {
+			_ = c.Conn.Close()
+			return
+		}
+	}
+
+	// Record everything that is read, so that it can be replayed if the
+	// connection is passed through
+	recorded := &bytes.Buffer{}
+	header, err := peekHeader(io.TeeReader(c.Conn, recorded))
+
+	// Slow clients must never be passed through
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		c.err = err
+		_ = c.Conn.Close()
+		return
+	}
+
+	// Deadline set by the caller is restored, not cleared, e.g. the one
+	// http.Server sets for ReadTimeout before the first Read
+	if c.headerReadTimeout > 0 {
+		c.err = c.setHeaderDeadline(time.Time{})
+		if c.err != nil {
+			_ = c.Conn.Close()
+			return
+		}
+	}
+
+	if err == nil {
+		// Header of an untrusted peer is consumed, so that it does not reach
+		// the application as data, but its addresses are not honored
+		if !c.untrusted {
+			c.header = header
+		}
+
+		c.reader = c.Conn
+		return
+	}
+
+	// Only connections without any header are passed through, since a
+	// malformed one may be an attempt to smuggle spoofed addresses
+	if _, ok := err.(*ProxyProtocolError); ok && !c.requireHeader {
+		c.reader = io.MultiReader(recorded, c.Conn)
+		return
+	}
+
+	c.err = err
+	_ = c.Conn.Close()
+}
+
+// setHeaderDeadline sets the deadline for reading the header, or removes it
+// if t is zero, keeping the read deadline set by the caller in effect.
+func (c *Conn) setHeaderDeadline(t time.Time) error {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+
+	c.headerDeadline = t
+	return c.Conn.SetReadDeadline(earlierDeadline(c.readDeadline, c.headerDeadline))
+}
+
+// SetDeadline sets read and write deadlines of the underlying connection.
+// While the header is being read, the read deadline is the earlier of t and
+// the limit set by Listener.MaxHeaderReadTime.
+func (c *Conn) SetDeadline(t time.Time) error {
+	err := c.Conn.SetWriteDeadline(t)
+	if err != nil {
+		return err
+	}
+
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the underlying connection. While
+// the header is being read, the earlier of t and the limit set by
+// Listener.MaxHeaderReadTime is applied, and t takes effect alone after that.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+
+	c.readDeadline = t
+	return c.Conn.SetReadDeadline(earlierDeadline(c.readDeadline, c.headerDeadline))
+}
+
+// earlierDeadline returns the earlier of two deadlines, where zero time means
+// no deadline at all.
+func earlierDeadline(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+
+	return a
+}
+
+// peekHeader reads the header like ReadHeader does, but gives up right after
+// the first byte if it cannot start a signature, so that a client that sends
+// less than a signature and waits for response is not kept waiting.
+func peekHeader(r io.Reader) (*Header, error) {
+	first := make([]byte, 1)
+	_, err := io.ReadFull(r, first)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] != V1Signature[0] && first[0] != protocolSignature[0] {
+		return nil, &ProxyProtocolError{Signature(), first}
+	}
+
+	return ReadHeader(io.MultiReader(bytes.NewReader(first), r))
+}
+
+func (c *Conn) Read(b []byte) (int, error) {
+	_, err := c.Header()
+	if err != nil {
+		return 0, err
+	}
+
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the source address from the header, reading the header
+// if necessary. The address of the underlying connection is returned if
+// there is no header, it is invalid, or it does not carry addresses.
+func (c *Conn) RemoteAddr() net.Addr {
+	header, _ := c.Header()
+	if header == nil || header.Command != CommandPROXY || header.ProxyAddress == nil {
+		return c.Conn.RemoteAddr()
+	}
+
+	source, _ := header.ProxyAddress.getAddresses()
+	return source
+}
+
+// LocalAddr returns the destination address from the header, reading the
+// header if necessary. The address of the underlying connection is returned
+// if there is no header, it is invalid, or it does not carry addresses.
+func (c *Conn) LocalAddr() net.Addr {
+	header, _ := c.Header()
+	if header == nil || header.Command != CommandPROXY || header.ProxyAddress == nil {
+		return c.Conn.LocalAddr()
+	}
+
+	_, destination := header.ProxyAddress.getAddresses()
+	return destination
+}
@@ -0,0 +1,96 @@
+package haproxy
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConn_Read(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		_, _ = client.Write(encodedHeaders[1])
+		_, _ = client.Write([]byte("hello"))
+	}()
+
+	conn := NewConn(server)
+	defer conn.Close()
+
+	data := make([]byte, 5)
+	_, err := io.ReadFull(conn, data)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	source := net.ParseIP("2345:0425:2CA1::0567:5673:23b5")
+	destination := net.ParseIP("2607:f0d0:1002:51::4")
+	assert.Equal(t, &net.UDPAddr{IP: source, Port: 32051}, conn.RemoteAddr())
+	assert.Equal(t, &net.UDPAddr{IP: destination, Port: 443}, conn.LocalAddr())
+}
+
+func TestConn_Read_FragmentedHeader(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	// Addresses arrive in several segments, each split in the middle of a field
+	go func() {
+		for _, segment := range [][]byte{encodedHeaders[1][:21], encodedHeaders[1][21:39], encodedHeaders[1][39:]} {
+			_, _ = client.Write(segment)
+		}
+
+		_, _ = client.Write([]byte("hello"))
+	}()
+
+	conn := NewConn(server)
+	defer conn.Close()
+
+	data := make([]byte, 5)
+	_, err := io.ReadFull(conn, data)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	source := net.ParseIP("2345:0425:2CA1::0567:5673:23b5")
+	assert.Equal(t, &net.UDPAddr{IP: source, Port: 32051}, conn.RemoteAddr())
+}
+
+func TestConn_Header(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		_, _ = client.Write(encodedHeaders[0])
+	}()
+
+	conn := NewConn(server)
+	defer conn.Close()
+
+	header, err := conn.Header()
+	assert.Nil(t, err)
+	assert.Equal(t, CommandPROXY, header.Command)
+
+	// Subsequent calls must not read anything
+	again, err := conn.Header()
+	assert.Nil(t, err)
+	assert.Same(t, header, again)
+}
+
+func TestConn_Header_Local(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		_, _ = client.Write([]byte{0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x0d, 0x0a, 0x51, 0x55, 0x49, 0x54, 0x0a, 0x20, 0x00, 0x00, 0x00})
+	}()
+
+	conn := NewConn(server)
+	defer conn.Close()
+
+	header, err := conn.Header()
+	assert.Nil(t, err)
+	assert.Equal(t, CommandLOCAL, header.Command)
+	assert.Equal(t, server.RemoteAddr(), conn.RemoteAddr())
+	assert.Equal(t, server.LocalAddr(), conn.LocalAddr())
+}
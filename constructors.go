@@ -0,0 +1,150 @@
+package haproxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"unicode/utf8"
+)
+
+// HeaderOption adds an extension to a header built by a constructor, such as
+// NewProxyHeaderTCP. Options are applied in order, so TLVs appear in the
+// header in the same order as the options are given.
+type HeaderOption func(h *Header) error
+
+// WithTLV adds a TLV of the given type, which is how extensions without a
+// dedicated option can be added.
+func WithTLV(t TLVType, value []byte) HeaderOption {
+	return func(h *Header) error {
+		return h.AddTLV(t, value)
+	}
+}
+
+// WithALPN adds the application-layer protocol negotiated with the client,
+// e.g. "h2".
+func WithALPN(protocol string) HeaderOption {
+	return WithTLV(TLVTypeALPN, []byte(protocol))
+}
+
+// WithAuthority adds the host name requested by the client, which must be
+// a valid UTF-8 string.
+func WithAuthority(authority string) HeaderOption {
+	return func(h *Header) error {
+		if !utf8.ValidString(authority) {
+			return fmt.Errorf("expected authority to be a valid UTF-8 string, but got %q", authority)
+		}
+
+		return h.AddTLV(TLVTypeAUTHORITY, []byte(authority))
+	}
+}
+
+// WithUniqueID adds an identifier of the connection, which must be at most
+// MaxUniqueIDLength bytes long.
+func WithUniqueID(id []byte) HeaderOption {
+	return func(h *Header) error {
+		return h.AddUniqueID(id)
+	}
+}
+
+// WithNetNS adds the name of the network namespace of the connection.
+func WithNetNS(name string) HeaderOption {
+	return func(h *Header) error {
+		return h.SetNetNS(name)
+	}
+}
+
+// applyOptions applies opts to the header, stopping at the first error.
+func (h *Header) applyOptions(opts []HeaderOption) error {
+	for _, opt := range opts {
+		err := opt(h)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NewDatagramHeader builds a PROXY header for UDP traffic, as forwarded by
+// QUIC and other datagram-based front-ends. Both addresses must be
+// *net.UDPAddr, so the header is always sent with TransportProtocolDGRAM.
+// Extensions can be added with opts.
+func NewDatagramHeader(src, dst net.Addr, opts ...HeaderOption) (*Header, error) {
+	if _, ok := src.(*net.UDPAddr); !ok {
+		return nil, fmt.Errorf("expected source address to be *net.UDPAddr, but got %T", src)
+	}
+
+	if _, ok := dst.(*net.UDPAddr); !ok {
+		return nil, fmt.Errorf("expected destination address to be *net.UDPAddr, but got %T", dst)
+	}
+
+	address, err := WrapAddress(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &Header{
+		Command:      CommandPROXY,
+		ProxyAddress: address,
+	}
+
+	err = header.applyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+// NewProxyHeaderTCP builds a PROXY header for TCP traffic. The address family
+// is chosen by the addresses, which must both be either IPv4 or IPv6.
+// Extensions can be added with opts, e.g.
+//
+//	NewProxyHeaderTCP(src, dst, WithAuthority("example.com"), WithUniqueID(id))
+func NewProxyHeaderTCP(src, dst *net.TCPAddr, opts ...HeaderOption) (*Header, error) {
+	if src == nil || dst == nil {
+		return nil, fmt.Errorf("expected all addresses to present, got source %v and destination %v", src, dst)
+	}
+
+	address, err := WrapAddress(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &Header{
+		Command:      CommandPROXY,
+		ProxyAddress: address,
+	}
+
+	err = header.applyOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+// NewLocalHeader builds a LOCAL header, which is sent by the proxy on its own
+// behalf, e.g. for health checks, and carries no addresses.
+func NewLocalHeader() *Header {
+	return &Header{Command: CommandLOCAL}
+}
+
+// WriteHeader builds a header for the given command and addresses and writes
+// it to w in one call, returning the number of bytes written. Addresses are
+// validated the same way as in WrapAddress. LOCAL headers may be written
+// without addresses by passing nil for both of them.
+func WriteHeader(w io.Writer, cmd Command, src, dst net.Addr) (int64, error) {
+	header := &Header{Command: cmd}
+
+	if cmd != CommandLOCAL || src != nil || dst != nil {
+		address, err := WrapAddress(src, dst)
+		if err != nil {
+			return 0, err
+		}
+
+		header.ProxyAddress = address
+	}
+
+	return header.WriteTo(w)
+}
@@ -0,0 +1,171 @@
+package haproxy
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDatagramHeader(t *testing.T) {
+	addresses := [][2]*net.UDPAddr{
+		{{IP: net.ParseIP("192.168.0.1"), Port: 56324}, {IP: net.ParseIP("192.168.0.11"), Port: 443}},
+		{{IP: net.ParseIP("2345:425:2ca1::567:5673:23b5"), Port: 56324}, {IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443}},
+	}
+
+	for _, pair := range addresses {
+		header, err := NewDatagramHeader(pair[0], pair[1])
+		assert.Nil(t, err)
+		signature, err := header.ProxyAddress.getSignature()
+		assert.Nil(t, err)
+		assert.Equal(t, TransportProtocolDGRAM, signature.TransportProtocol)
+
+		buffer := &bytes.Buffer{}
+		_, err = header.WriteTo(buffer)
+		assert.Nil(t, err)
+
+		var decoded Header
+		_, err = decoded.ReadFrom(buffer)
+		assert.Nil(t, err)
+
+		source, destination := decoded.ProxyAddress.getAddresses()
+		assert.IsType(t, &net.UDPAddr{}, source)
+		assert.True(t, pair[0].IP.Equal(source.(*net.UDPAddr).IP))
+		assert.Equal(t, pair[0].Port, source.(*net.UDPAddr).Port)
+		assert.True(t, pair[1].IP.Equal(destination.(*net.UDPAddr).IP))
+		assert.Equal(t, pair[1].Port, destination.(*net.UDPAddr).Port)
+	}
+}
+
+func TestNewDatagramHeader_TCP(t *testing.T) {
+	udp := &net.UDPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324}
+	tcp := &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443}
+
+	_, err := NewDatagramHeader(tcp, tcp)
+	assert.NotNil(t, err)
+
+	_, err = NewDatagramHeader(udp, tcp)
+	assert.NotNil(t, err)
+}
+
+func TestNewProxyHeaderTCP(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324}
+	dst := &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443}
+
+	header, err := NewProxyHeaderTCP(src, dst)
+	assert.Nil(t, err)
+	assert.Equal(t, CommandPROXY, header.Command)
+	assert.IsType(t, &IPv4Address{}, header.ProxyAddress)
+	assert.Nil(t, header.SelfConsistent())
+
+	_, err = NewProxyHeaderTCP(src, &net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443})
+	assert.NotNil(t, err)
+
+	_, err = NewProxyHeaderTCP(src, nil)
+	assert.NotNil(t, err)
+}
+
+func TestNewLocalHeader(t *testing.T) {
+	header := NewLocalHeader()
+	assert.Equal(t, CommandLOCAL, header.Command)
+	assert.Nil(t, header.ProxyAddress)
+	assert.Nil(t, header.SelfConsistent())
+}
+
+func TestWriteHeader(t *testing.T) {
+	addresses := [][2]net.Addr{
+		{&net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324}, &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443}},
+		{&net.UDPAddr{IP: net.ParseIP("2345:425:2ca1::567:5673:23b5"), Port: 56324}, &net.UDPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443}},
+		{&net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"}, &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unix"}},
+	}
+
+	for _, pair := range addresses {
+		buffer := &bytes.Buffer{}
+		n, err := WriteHeader(buffer, CommandPROXY, pair[0], pair[1])
+		assert.Nil(t, err)
+		assert.Equal(t, int64(buffer.Len()), n)
+
+		var decoded Header
+		_, err = decoded.ReadFrom(buffer)
+		assert.Nil(t, err)
+		assert.Equal(t, CommandPROXY, decoded.Command)
+
+		source, destination := decoded.ProxyAddress.getAddresses()
+		assert.Equal(t, pair[0].String(), source.String())
+		assert.Equal(t, pair[1].String(), destination.String())
+		assert.Equal(t, pair[0].Network(), source.Network())
+	}
+}
+
+func TestWriteHeader_Local(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	n, err := WriteHeader(buffer, CommandLOCAL, nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(16), n)
+	assert.Equal(t, MustEncode(NewLocalHeader()), buffer.Bytes())
+}
+
+func TestWriteHeader_InvalidAddresses(t *testing.T) {
+	tcp := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324}
+	udp := &net.UDPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443}
+	ipv6 := &net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443}
+
+	pairs := [][2]net.Addr{{tcp, udp}, {tcp, ipv6}, {tcp, nil}, {nil, nil}}
+	for _, pair := range pairs {
+		buffer := &bytes.Buffer{}
+		n, err := WriteHeader(buffer, CommandPROXY, pair[0], pair[1])
+		assert.NotNil(t, err, pair)
+		assert.Equal(t, int64(0), n)
+		assert.Equal(t, 0, buffer.Len())
+	}
+}
+
+func TestNewProxyHeaderTCP_Options(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324}
+	dst := &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443}
+
+	header, err := NewProxyHeaderTCP(src, dst,
+		WithUniqueID([]byte("request-42")),
+		WithAuthority("example.com"),
+		WithALPN("h2"),
+		WithNetNS("tenant-a"),
+		WithTLV(0xE0, []byte{0x01}),
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, []TLV{
+		{TLVTypeUNIQUEID, []byte("request-42")},
+		{TLVTypeAUTHORITY, []byte("example.com")},
+		{TLVTypeALPN, []byte("h2")},
+		{TLVTypeNETNS, []byte("tenant-a")},
+		{0xE0, []byte{0x01}},
+	}, header.TLVs)
+
+	var decoded Header
+	assert.Nil(t, decoded.UnmarshalBinary(MustEncode(header)))
+
+	authority, ok := decoded.Authority()
+	assert.True(t, ok)
+	assert.Equal(t, "example.com", authority)
+
+	id, ok := decoded.UniqueID()
+	assert.True(t, ok)
+	assert.Equal(t, []byte("request-42"), id)
+}
+
+func TestNewProxyHeaderTCP_InvalidOptions(t *testing.T) {
+	src := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324}
+	dst := &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443}
+
+	_, err := NewProxyHeaderTCP(src, dst, WithAuthority("\xff\xfe"))
+	assert.NotNil(t, err)
+
+	_, err = NewProxyHeaderTCP(src, dst, WithUniqueID(make([]byte, MaxUniqueIDLength+1)))
+	assert.NotNil(t, err)
+
+	_, err = NewDatagramHeader(
+		&net.UDPAddr{IP: src.IP, Port: src.Port}, &net.UDPAddr{IP: dst.IP, Port: dst.Port},
+		WithTLV(0xE0, make([]byte, 70000)),
+	)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,147 @@
+package haproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// deadlineReader is implemented by readers that can be interrupted by
+// a deadline, such as net.Conn.
+type deadlineReader interface {
+	io.Reader
+	SetReadDeadline(t time.Time) error
+}
+
+// contextDoneDelay bounds the time ReadFromContext waits for ctx to notice
+// that its deadline has passed.
+const contextDoneDelay = 100 * time.Millisecond
+
+// ReadFromContext reads a version 2 header like ReadFrom does, but gives up
+// when ctx is done. In that case the returned error wraps ctx.Err(), and the
+// number of bytes read so far is returned along with it.
+//
+// A blocked read from a plain io.Reader can not be interrupted, so ctx is only
+// honored while reading if r has a SetReadDeadline method, like net.Conn does.
+// Passing a net.Conn is the intended way to bound the time spent on a peer
+// that stalls in the middle of the header. Any read deadline already set on
+// such reader is overridden, and it is cleared before returning.
+func (h *Header) ReadFromContext(ctx context.Context, r io.Reader) (m int64, err error) {
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("unable to read header: %w", err)
+	}
+
+	conn, ok := r.(deadlineReader)
+	if !ok {
+		return h.ReadFrom(r)
+	}
+
+	// Any deadline set on the reader before is replaced, so that a timeout
+	// always means that ctx is done
+	deadline, _ := ctx.Deadline()
+	err = conn.SetReadDeadline(deadline)
+	if err != nil {
+		return 0, err
+	}
+
+	// Cancellation has no deadline to set in advance, so blocked read is
+	// interrupted by moving the deadline to the past
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			_ = conn.SetReadDeadline(time.Unix(1, 0))
+		case <-stop:
+		}
+	}()
+
+	m, err = h.ReadFrom(r)
+	close(stop)
+	<-stopped
+
+	resetErr := conn.SetReadDeadline(time.Time{})
+
+	// The deadline may pass slightly before ctx notices it. Timeouts that r
+	// produces on its own must not wait for ctx, which may never be done
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+			timer := time.NewTimer(contextDoneDelay)
+			select {
+			case <-ctx.Done():
+			case <-timer.C:
+			}
+
+			timer.Stop()
+		}
+	}
+
+	if err != nil && ctx.Err() != nil {
+		return m, fmt.Errorf("unable to read header: %w", ctx.Err())
+	}
+
+	if err == nil {
+		err = resetErr
+	}
+
+	return m, err
+}
+
+// HeaderTimeoutError is returned by ReadHeaderConn when the peer does not
+// send the whole header in time.
+type HeaderTimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+func (e HeaderTimeoutError) Error() string {
+	return fmt.Sprintf("header was not received within %s: %s", e.Timeout, e.Err)
+}
+
+func (e HeaderTimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// ReadHeaderConn reads a header of any version from c like ReadHeader does,
+// giving the peer at most timeout to send it, which protects servers from
+// peers that stall in the middle of the header. A zero timeout sets no
+// deadline. Any read deadline already set on c is overridden, and it is
+// cleared before returning, even on error.
+//
+// When the header is not received in time, *HeaderTimeoutError is returned.
+// Part of the header may already be consumed by then, so the connection
+// should be closed.
+func ReadHeaderConn(c net.Conn, timeout time.Duration) (*Header, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	err := c.SetReadDeadline(deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := ReadHeader(c)
+	resetErr := c.SetReadDeadline(time.Time{})
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return nil, &HeaderTimeoutError{Timeout: timeout, Err: err}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if resetErr != nil {
+		return nil, resetErr
+	}
+
+	return header, nil
+}
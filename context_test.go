@@ -0,0 +1,161 @@
+package haproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader_ReadFromContext(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		_, _ = headers[0].WriteTo(client)
+	}()
+
+	var header Header
+	n, err := header.ReadFromContext(context.Background(), server)
+	assert.Nil(t, err)
+	assert.Equal(t, len(expectedEncodedHeaders[0]), int(n))
+	assert.Equal(t, headers[0].Command, header.Command)
+}
+
+func TestHeader_ReadFromContext_Timeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// Peer sends the signature and stalls
+	go func() {
+		_, _ = client.Write(Signature())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var header Header
+	n, err := header.ReadFromContext(ctx, server)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.Equal(t, len(Signature()), int(n))
+}
+
+func TestHeader_ReadFromContext_Cancel(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	var header Header
+	n, err := header.ReadFromContext(ctx, server)
+	assert.True(t, errors.Is(err, context.Canceled), err)
+	assert.Equal(t, 0, int(n))
+}
+
+func TestHeader_ReadFromContext_Done(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var header Header
+	n, err := header.ReadFromContext(ctx, bytes.NewReader(expectedEncodedHeaders[0]))
+	assert.True(t, errors.Is(err, context.Canceled), err)
+	assert.Equal(t, 0, int(n))
+}
+
+// timeoutReader fails every read with a wrapped timeout, like a connection
+// with its own deadline does.
+type timeoutReader struct{}
+
+func (timeoutReader) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("read failed: %w", os.ErrDeadlineExceeded)
+}
+
+func (timeoutReader) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+func TestHeader_ReadFromContext_ReaderTimeout(t *testing.T) {
+	withDeadline, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	for _, ctx := range []context.Context{context.Background(), withDeadline} {
+		done := make(chan error, 1)
+		go func() {
+			var header Header
+			_, err := header.ReadFromContext(ctx, timeoutReader{})
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			assert.True(t, errors.Is(err, os.ErrDeadlineExceeded), err)
+			assert.Nil(t, ctx.Err())
+		case <-time.After(time.Second):
+			t.Fatal("ReadFromContext is waiting for context that is never done")
+		}
+	}
+}
+
+func TestReadHeaderConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		_, _ = client.Write(append(append([]byte{}, encodedHeaders[0]...), "hello"...))
+	}()
+
+	header, err := ReadHeaderConn(server, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, headers[0].Command, header.Command)
+
+	// Deadline is cleared, so reading after the header is not limited
+	data := make([]byte, 5)
+	_, err = io.ReadFull(server, data)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("hello"), data)
+}
+
+func TestReadHeaderConn_Timeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// Peer sends the header slowly, one byte at a time
+	go func() {
+		for _, b := range encodedHeaders[0] {
+			if _, err := client.Write([]byte{b}); err != nil {
+				return
+			}
+
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	_, err := ReadHeaderConn(server, 50*time.Millisecond)
+	timeoutErr := &HeaderTimeoutError{}
+	assert.True(t, errors.As(err, &timeoutErr), err)
+	assert.Equal(t, 50*time.Millisecond, timeoutErr.Timeout)
+	assert.True(t, errors.Is(err, os.ErrDeadlineExceeded), err)
+
+	// Connection is left without deadline
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = client.Write([]byte("hello"))
+	}()
+
+	data := make([]byte, 1)
+	_, err = server.Read(data)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,26 @@
+package haproxy
+
+import "io"
+
+// CopyWithHeader reads a header of any version from src, writes it to dst in
+// version 2 format, and then copies the rest of src to dst until EOF, like
+// io.Copy does. It covers the common proxy loop that forwards a connection
+// to a backend along with the identity of the client. The parsed header is
+// returned along with the total number of bytes written to dst, including
+// the header, so that the caller can log the client. The header is returned
+// even if copying the payload fails. If src holds no valid header, nothing is
+// written to dst.
+func CopyWithHeader(dst io.Writer, src io.Reader) (*Header, int64, error) {
+	header, rest, err := ReadHeaderFrom(src)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	n, err := header.WriteTo(dst)
+	if err != nil {
+		return header, n, err
+	}
+
+	copied, err := io.Copy(dst, rest)
+	return header, n + copied, err
+}
@@ -0,0 +1,48 @@
+package haproxy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyWithHeader(t *testing.T) {
+	payload := strings.Repeat("GET / HTTP/1.1\r\n\r\n", 1000)
+	inputs := [][]byte{
+		append(append([]byte{}, expectedEncodedHeaders[0]...), payload...),
+		[]byte("PROXY TCP4 127.0.0.1 127.0.0.1 42446 1338\r\n" + payload),
+	}
+
+	for _, input := range inputs {
+		dst := &bytes.Buffer{}
+		header, n, err := CopyWithHeader(dst, bytes.NewReader(input))
+		assert.Nil(t, err)
+		assert.True(t, headers[0].Equal(header))
+		assert.Equal(t, int64(dst.Len()), n)
+
+		// Header is forwarded in version 2 format, followed by the payload
+		expected := append(append([]byte{}, expectedEncodedHeaders[0]...), payload...)
+		assert.Equal(t, expected, dst.Bytes())
+	}
+}
+
+func TestCopyWithHeader_NoHeader(t *testing.T) {
+	dst := &bytes.Buffer{}
+	header, n, err := CopyWithHeader(dst, strings.NewReader("GET / HTTP/1.1\r\n\r\n"))
+	assert.IsType(t, &ProxyProtocolError{}, err)
+	assert.Nil(t, header)
+	assert.Equal(t, int64(0), n)
+	assert.Equal(t, 0, dst.Len())
+}
+
+func TestCopyWithHeader_WriteError(t *testing.T) {
+	input := append(append([]byte{}, expectedEncodedHeaders[0]...), "hello"...)
+
+	dst := &failingWriter{limit: len(expectedEncodedHeaders[0]) + 2}
+	header, n, err := CopyWithHeader(dst, bytes.NewReader(input))
+	assert.Equal(t, errWriteFailed, err)
+	assert.True(t, headers[0].Equal(header))
+	assert.Equal(t, int64(dst.limit), n)
+}
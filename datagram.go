@@ -0,0 +1,38 @@
+package haproxy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ParseDatagram parses a version 2 header in the beginning of a datagram, as
+// prepended by UDP proxies to the first datagram of a flow, e.g. after
+// reading it from net.PacketConn, and returns it along with the offset of the
+// payload within the datagram. Version 1 headers are only defined for TCP, so
+// they are rejected.
+//
+// Unlike a stream, a datagram is never continued, so a datagram shorter than
+// the header it declares is an error. It wraps IncompleteHeaderError, which
+// tells how many bytes were expected.
+func ParseDatagram(datagram []byte) (*Header, int, error) {
+	// Too short datagram can only be told apart from garbage by its prefix
+	if len(datagram) < len(protocolSignature) && !strings.HasPrefix(protocolSignature, string(datagram)) {
+		return nil, 0, &ProxyProtocolError{Signature(), datagram}
+	}
+
+	reader := bytes.NewReader(datagram)
+
+	var header Header
+	_, err := header.ReadFrom(reader)
+	if errors.Is(err, ErrIncompleteHeader) {
+		return nil, 0, fmt.Errorf("datagram is shorter than its header: %w", err)
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &header, len(datagram) - reader.Len(), nil
+}
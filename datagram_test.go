@@ -0,0 +1,51 @@
+package haproxy
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDatagram(t *testing.T) {
+	payload := []byte("QUIC initial packet")
+	datagram := append(append([]byte{}, encodedHeaders[1]...), payload...)
+
+	header, offset, err := ParseDatagram(datagram)
+	assert.Nil(t, err)
+	assert.Equal(t, len(encodedHeaders[1]), offset)
+	assert.Equal(t, payload, datagram[offset:])
+
+	source, _ := header.ProxyAddress.getAddresses()
+	assert.IsType(t, &net.UDPAddr{}, source)
+
+	// Datagram may consist of the header alone
+	_, offset, err = ParseDatagram(encodedHeaders[1])
+	assert.Nil(t, err)
+	assert.Equal(t, len(encodedHeaders[1]), offset)
+}
+
+func TestParseDatagram_Truncated(t *testing.T) {
+	datagram := encodedHeaders[1][:len(encodedHeaders[1])-4]
+
+	_, _, err := ParseDatagram(datagram)
+	assert.True(t, errors.Is(err, ErrIncompleteHeader), err)
+
+	incomplete := &IncompleteHeaderError{}
+	assert.True(t, errors.As(err, &incomplete))
+	assert.Equal(t, len(encodedHeaders[1]), incomplete.Expected)
+}
+
+func TestParseDatagram_NoHeader(t *testing.T) {
+	datagrams := [][]byte{
+		[]byte("QUIC"),
+		[]byte("QUIC initial packet"),
+		[]byte("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n"),
+	}
+
+	for _, datagram := range datagrams {
+		_, _, err := ParseDatagram(datagram)
+		assert.IsType(t, &ProxyProtocolError{}, err, string(datagram))
+	}
+}
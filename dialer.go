@@ -0,0 +1,64 @@
+package haproxy
+
+import (
+	"context"
+	"net"
+)
+
+// Dialer establishes connections and writes a PROXY header to each of them
+// before returning it, so that a PROXY-aware backend learns addresses of
+// the original connection.
+type Dialer struct {
+	// Dialer is used to establish connections.
+	Dialer net.Dialer
+
+	// Command is sent in every header. CommandPROXY forwards addresses given
+	// to Dial, while CommandLOCAL is meant for connections made by the proxy
+	// on its own behalf, e.g. health checks, and allows addresses to be nil.
+	Command Command
+}
+
+// Dial connects to the address on the named network and writes a header
+// with src and dst addresses. See net.Dial for the description of network
+// and address parameters.
+func (d *Dialer) Dial(network, address string, src, dst net.Addr) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address, src, dst)
+}
+
+// DialContext is like Dial, but uses the provided context for establishing
+// the connection.
+func (d *Dialer) DialContext(ctx context.Context, network, address string, src, dst net.Addr) (net.Conn, error) {
+	header := Header{Command: d.Command}
+	if d.Command == CommandPROXY || src != nil || dst != nil {
+		proxyAddress, err := WrapAddress(src, dst)
+		if err != nil {
+			return nil, err
+		}
+
+		header.ProxyAddress = proxyAddress
+	}
+
+	conn, err := d.Dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = header.WriteTo(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// ProxyTo connects to a TCP backend on behalf of the client of inbound, and
+// writes a PROXY header that carries addresses of inbound connection, so
+// that the backend sees the real client: inbound.RemoteAddr becomes the
+// source address, and inbound.LocalAddr the destination. If inbound is a
+// *Conn, addresses from its header are forwarded, which lets proxies be
+// chained. The provided context is used for establishing the connection.
+func ProxyTo(ctx context.Context, inbound net.Conn, backendAddr string) (net.Conn, error) {
+	dialer := &Dialer{Command: CommandPROXY}
+	return dialer.DialContext(ctx, "tcp", backendAddr, inbound.RemoteAddr(), inbound.LocalAddr())
+}
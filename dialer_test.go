@@ -0,0 +1,117 @@
+package haproxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialer_Dial(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	listener := NewListener(inner)
+	defer listener.Close()
+
+	src := &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324}
+	dst := &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443}
+
+	dialer := &Dialer{Command: CommandPROXY}
+	client, err := dialer.Dial("tcp", inner.Addr().String(), src, dst)
+	assert.Nil(t, err)
+	defer client.Close()
+
+	_, err = client.Write([]byte("hello"))
+	assert.Nil(t, err)
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	data := make([]byte, 5)
+	_, err = io.ReadFull(conn, data)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.Equal(t, "192.168.0.1:56324", conn.RemoteAddr().String())
+	assert.Equal(t, "192.168.0.11:443", conn.LocalAddr().String())
+}
+
+func TestDialer_Dial_Local(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	listener := NewListener(inner)
+	defer listener.Close()
+
+	dialer := &Dialer{Command: CommandLOCAL}
+	client, err := dialer.Dial("tcp", inner.Addr().String(), nil, nil)
+	assert.Nil(t, err)
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	header, err := conn.(*Conn).Header()
+	assert.Nil(t, err)
+	assert.Equal(t, CommandLOCAL, header.Command)
+	assert.Nil(t, header.ProxyAddress)
+	assert.Equal(t, client.LocalAddr(), conn.RemoteAddr())
+}
+
+func TestDialer_Dial_MissingAddress(t *testing.T) {
+	dialer := &Dialer{Command: CommandPROXY}
+	_, err := dialer.Dial("tcp", "127.0.0.1:1", nil, nil)
+	assert.NotNil(t, err)
+}
+
+func TestProxyTo(t *testing.T) {
+	frontend, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer frontend.Close()
+
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	listener := NewListener(backend)
+	defer listener.Close()
+
+	client, err := net.Dial("tcp", frontend.Addr().String())
+	assert.Nil(t, err)
+	defer client.Close()
+
+	inbound, err := frontend.Accept()
+	assert.Nil(t, err)
+	defer inbound.Close()
+
+	outbound, err := ProxyTo(context.Background(), inbound, backend.Addr().String())
+	assert.Nil(t, err)
+	defer outbound.Close()
+
+	_, err = outbound.Write([]byte("hello"))
+	assert.Nil(t, err)
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	data := make([]byte, 5)
+	_, err = io.ReadFull(conn, data)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.Equal(t, client.LocalAddr().String(), conn.RemoteAddr().String())
+	assert.Equal(t, frontend.Addr().String(), conn.LocalAddr().String())
+}
+
+func TestProxyTo_UnsupportedAddress(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// Addresses of a pipe can not be sent in a header
+	_, err := ProxyTo(context.Background(), server, "127.0.0.1:1")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,196 @@
+package haproxy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// dumpRowLength is the number of bytes printed on a single line of DumpHeader output.
+const dumpRowLength = 16
+
+// DumpHeader writes a human-readable hex dump of serialized version 2 header
+// to w, labeling every field: signature, version and command, address family
+// and transport protocol, length, addresses, ports and each TLV. Bytes that
+// follow the header are printed as trailing data. Truncated or malformed input
+// is dumped as far as it can be interpreted.
+func DumpHeader(w io.Writer, data []byte) {
+	d := &dumper{w: w, data: data}
+
+	signature, ok := d.field(len(protocolSignature), "signature")
+	if !ok {
+		return
+	}
+
+	if string(signature) != protocolSignature {
+		d.rest("unexpected data, there is no signature")
+		return
+	}
+
+	version, ok := d.peek(1)
+	if !ok {
+		return
+	}
+
+	d.field(1, fmt.Sprintf("version %x, command %x", version[0]>>4, version[0]&0b1111))
+
+	protocol, ok := d.peek(1)
+	if !ok {
+		return
+	}
+
+	family, transport := AddressFamily(protocol[0]>>4), TransportProtocol(protocol[0]&0b1111)
+	d.field(1, fmt.Sprintf("address family %x, transport protocol %x", byte(family), byte(transport)))
+
+	length, ok := d.peek(2)
+	if !ok {
+		return
+	}
+
+	addressLength := int(binary.BigEndian.Uint16(length))
+	d.field(2, fmt.Sprintf("length %d", addressLength))
+
+	end := d.offset + addressLength
+	if end > len(data) {
+		end = len(data)
+	}
+
+	// Fields of the address block must not be read beyond the declared length
+	d.data = data[:end]
+
+	var fields []dumpField
+	switch family {
+	case AddressFamilyINET:
+		fields = ipDumpFields(net.IPv4len)
+	case AddressFamilyINET6:
+		fields = ipDumpFields(net.IPv6len)
+	case AddressFamilyUNIX:
+		fields = []dumpField{{108, "source address", formatDumpPath}, {108, "destination address", formatDumpPath}}
+	}
+
+	for _, f := range fields {
+		if d.offset+f.length > len(d.data) {
+			d.print(d.data[d.offset:], f.label+" (truncated)")
+			break
+		}
+
+		d.field(f.length, f.label+" "+f.format(d.data[d.offset:d.offset+f.length]))
+	}
+
+	for d.offset < len(d.data) {
+		tlv, ok := d.peek(3)
+		if !ok {
+			break
+		}
+
+		tlvLength := int(binary.BigEndian.Uint16(tlv[1:]))
+		d.field(3, fmt.Sprintf("TLV type 0x%02x, length %d", tlv[0], tlvLength))
+
+		value, ok := d.peek(tlvLength)
+		if !ok {
+			break
+		}
+
+		d.field(tlvLength, "TLV value "+formatDumpValue(value))
+	}
+
+	d.data = data
+	if d.offset < len(d.data) {
+		d.rest(fmt.Sprintf("trailing data (%d bytes)", len(d.data)-d.offset))
+	}
+}
+
+type dumpField struct {
+	length int
+	label  string
+	format func([]byte) string
+}
+
+func ipDumpFields(length int) []dumpField {
+	return []dumpField{
+		{length, "source address", formatDumpIP},
+		{length, "destination address", formatDumpIP},
+		{2, "source port", formatDumpPort},
+		{2, "destination port", formatDumpPort},
+	}
+}
+
+func formatDumpIP(data []byte) string {
+	return net.IP(data).String()
+}
+
+func formatDumpPort(data []byte) string {
+	return fmt.Sprint(binary.BigEndian.Uint16(data))
+}
+
+func formatDumpPath(data []byte) string {
+	return fmt.Sprintf("%q", unixPath(data))
+}
+
+func formatDumpValue(data []byte) string {
+	for _, b := range data {
+		if b < 0x20 || b > 0x7e {
+			return fmt.Sprintf("(%d bytes)", len(data))
+		}
+	}
+
+	return fmt.Sprintf("%q", data)
+}
+
+type dumper struct {
+	w      io.Writer
+	data   []byte
+	offset int
+}
+
+// peek returns next n bytes without consuming them. If there are not enough
+// bytes, the remaining ones are dumped as truncated and false is returned.
+func (d *dumper) peek(n int) ([]byte, bool) {
+	if d.offset+n > len(d.data) {
+		d.print(d.data[d.offset:], "truncated")
+		return nil, false
+	}
+
+	return d.data[d.offset : d.offset+n], true
+}
+
+// field dumps next n bytes with the given label. If there are not enough
+// bytes, the remaining ones are dumped as truncated and false is returned.
+func (d *dumper) field(n int, label string) ([]byte, bool) {
+	if d.offset+n > len(d.data) {
+		d.print(d.data[d.offset:], label+" (truncated)")
+		return nil, false
+	}
+
+	data := d.data[d.offset : d.offset+n]
+	d.print(data, label)
+	return data, true
+}
+
+// rest dumps all remaining bytes with the given label.
+func (d *dumper) rest(label string) {
+	if d.offset < len(d.data) {
+		d.print(d.data[d.offset:], label)
+	}
+}
+
+func (d *dumper) print(data []byte, label string) {
+	for i := 0; i < len(data) || i == 0; i += dumpRowLength {
+		row := data[i:]
+		if len(row) > dumpRowLength {
+			row = row[:dumpRowLength]
+		}
+
+		hex := make([]string, len(row))
+		for j, b := range row {
+			hex[j] = fmt.Sprintf("%02x", b)
+		}
+
+		_, _ = fmt.Fprintf(d.w, "%04x  %-*s  %s\n", d.offset+i, dumpRowLength*3-1, strings.Join(hex, " "), label)
+		label = ""
+	}
+
+	d.offset += len(data)
+}
@@ -0,0 +1,49 @@
+package haproxy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpHeader(t *testing.T) {
+	data := []byte{
+		0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x0d, 0x0a, 0x51, 0x55, 0x49, 0x54, 0x0a, 0x21, 0x11, 0x00, 0x12,
+		0x7f, 0x00, 0x00, 0x01, 0x7f, 0x00, 0x00, 0x01, 0xa5, 0xce, 0x05, 0x3a, 0x02, 0x00, 0x03, 0x66,
+		0x6f, 0x6f, 0x47, 0x45, 0x54,
+	}
+
+	buffer := &bytes.Buffer{}
+	DumpHeader(buffer, data)
+
+	expected := []string{
+		"0000  0d 0a 0d 0a 00 0d 0a 51 55 49 54 0a              signature",
+		"000c  21                                               version 2, command 1",
+		"000d  11                                               address family 1, transport protocol 1",
+		"000e  00 12                                            length 18",
+		"0010  7f 00 00 01                                      source address 127.0.0.1",
+		"0014  7f 00 00 01                                      destination address 127.0.0.1",
+		"0018  a5 ce                                            source port 42446",
+		"001a  05 3a                                            destination port 1338",
+		"001c  02 00 03                                         TLV type 0x02, length 3",
+		"001f  66 6f 6f                                         TLV value \"foo\"",
+		"0022  47 45 54                                         trailing data (3 bytes)",
+	}
+
+	assert.Equal(t, strings.Join(expected, "\n")+"\n", buffer.String())
+}
+
+func TestDumpHeader_Truncated(t *testing.T) {
+	for _, data := range encodedHeaders {
+		for i := 0; i <= len(data); i++ {
+			buffer := &bytes.Buffer{}
+			DumpHeader(buffer, data[:i])
+
+			if i > 0 {
+				assert.NotEmpty(t, buffer.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package haproxy
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+)
+
+// equalAddr compares addresses by value, treating 4-byte and 16-byte
+// representations of the same IPv4 address as equal.
+func equalAddr(a, b net.Addr) bool {
+	switch a := a.(type) {
+	case *net.TCPAddr:
+		b, ok := b.(*net.TCPAddr)
+		if !ok || a == nil || b == nil {
+			return ok && a == b
+		}
+
+		return a.IP.Equal(b.IP) && a.Port == b.Port && a.Zone == b.Zone
+	case *net.UDPAddr:
+		b, ok := b.(*net.UDPAddr)
+		if !ok || a == nil || b == nil {
+			return ok && a == b
+		}
+
+		return a.IP.Equal(b.IP) && a.Port == b.Port && a.Zone == b.Zone
+	case *net.UnixAddr:
+		b, ok := b.(*net.UnixAddr)
+		if !ok || a == nil || b == nil {
+			return ok && a == b
+		}
+
+		return a.Name == b.Name && a.Net == b.Net
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// equalProxyAddress compares address family, transport protocol and both
+// addresses, regardless of whether a and b are pointers or values.
+func equalProxyAddress(a, b ProxyAddress) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	signatureA, err := a.getSignature()
+	if err != nil {
+		return false
+	}
+
+	signatureB, err := b.getSignature()
+	if err != nil || signatureA != signatureB {
+		return false
+	}
+
+	sourceA, destinationA := a.getAddresses()
+	sourceB, destinationB := b.getAddresses()
+	return equalAddr(sourceA, sourceB) && equalAddr(destinationA, destinationB)
+}
+
+func equalTLVs(a, b []TLV) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Type != b[i].Type || !bytes.Equal(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Equal reports whether both headers describe the same connection: they have
+// the same command, and PROXY headers also have the same address family,
+// transport protocol, addresses and TLVs. IPs are compared by value, so the
+// 4-byte and the 16-byte forms of an IPv4 address are equal. Addresses and
+// TLVs of LOCAL headers are not sent, so they are not compared.
+func (h Header) Equal(other *Header) bool {
+	if other == nil || h.Command != other.Command {
+		return false
+	}
+
+	if h.Command != CommandPROXY {
+		return true
+	}
+
+	return h.Unsupported == other.Unsupported &&
+		equalProxyAddress(h.ProxyAddress, other.ProxyAddress) && equalTLVs(h.TLVs, other.TLVs)
+}
+
+// Equal reports whether other has the same transport protocol, IPs and
+// ports. IPs are compared by value, regardless of their length.
+func (a IPv4Address) Equal(other ProxyAddress) bool {
+	return equalProxyAddress(a, other)
+}
+
+// Equal reports whether other has the same transport protocol, IPs and
+// ports. IPs are compared by value, regardless of their length.
+func (a IPv6Address) Equal(other ProxyAddress) bool {
+	return equalProxyAddress(a, other)
+}
+
+// Equal reports whether other has the same transport protocol and paths.
+func (a UnixAddr) Equal(other ProxyAddress) bool {
+	return equalProxyAddress(a, other)
+}
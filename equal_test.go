@@ -0,0 +1,129 @@
+package haproxy
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader_ReadFrom_UDPRoundTrip(t *testing.T) {
+	udp := &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.UDPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+			DestinationAddr: &net.UDPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+		},
+	}
+
+	tcp := &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+		},
+	}
+
+	var decoded Header
+	_, err := decoded.ReadFrom(bytes.NewReader(MustEncode(udp)))
+	assert.Nil(t, err)
+
+	source, destination := decoded.ProxyAddress.getAddresses()
+	assert.IsType(t, &net.UDPAddr{}, source)
+	assert.IsType(t, &net.UDPAddr{}, destination)
+
+	assert.True(t, equalProxyAddress(udp.ProxyAddress, decoded.ProxyAddress))
+	assert.False(t, equalProxyAddress(tcp.ProxyAddress, decoded.ProxyAddress))
+	assert.Nil(t, udp.SelfConsistent())
+}
+
+func TestEqualAddr(t *testing.T) {
+	ip := net.ParseIP("192.168.0.1")
+
+	assert.True(t, equalAddr(&net.TCPAddr{IP: ip, Port: 443}, &net.TCPAddr{IP: ip.To4(), Port: 443}))
+	assert.True(t, equalAddr(&net.UDPAddr{IP: ip, Port: 443}, &net.UDPAddr{IP: ip.To4(), Port: 443}))
+	assert.False(t, equalAddr(&net.TCPAddr{IP: ip, Port: 443}, &net.UDPAddr{IP: ip, Port: 443}))
+	assert.False(t, equalAddr(&net.UDPAddr{IP: ip, Port: 443}, &net.TCPAddr{IP: ip, Port: 443}))
+	assert.False(t, equalAddr(&net.TCPAddr{IP: ip, Port: 443}, &net.TCPAddr{IP: ip, Port: 80}))
+}
+
+func TestHeader_Equal(t *testing.T) {
+	ipv4 := &Header{Command: CommandPROXY, ProxyAddress: &IPv4Address{
+		SourceAddr:      &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+		DestinationAddr: &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+	}}
+	ipv6 := &Header{Command: CommandPROXY, ProxyAddress: &IPv6Address{
+		SourceAddr:      &net.UDPAddr{IP: net.ParseIP("2345:425:2ca1::567:5673:23b5"), Port: 56324},
+		DestinationAddr: &net.UDPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443},
+	}}
+	unix := &Header{Command: CommandPROXY, ProxyAddress: &UnixAddr{
+		SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+		DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unix"},
+	}}
+
+	for _, header := range []*Header{ipv4, ipv6, unix} {
+		var decoded Header
+		assert.Nil(t, decoded.UnmarshalBinary(MustEncode(header)))
+		assert.True(t, header.Equal(&decoded), header.String())
+		assert.True(t, decoded.Equal(header.Clone()), header.String())
+
+		withTLVs := header.Clone()
+		withTLVs.TLVs = []TLV{{TLVTypeALPN, []byte("h2")}}
+		assert.False(t, header.Equal(withTLVs), header.String())
+		assert.False(t, header.Equal(nil))
+	}
+
+	assert.False(t, ipv4.Equal(ipv6))
+	assert.False(t, ipv6.Equal(unix))
+
+	// 4-byte and 16-byte forms of the same IPv4 address
+	short := &Header{Command: CommandPROXY, ProxyAddress: IPv4Address{
+		SourceAddr:      &net.TCPAddr{IP: net.IPv4(192, 168, 0, 1).To4(), Port: 56324},
+		DestinationAddr: &net.TCPAddr{IP: net.IPv4(192, 168, 0, 11).To4(), Port: 443},
+	}}
+	assert.True(t, ipv4.Equal(short))
+
+	// Addresses of LOCAL headers are not sent
+	local := &Header{Command: CommandLOCAL, ProxyAddress: ipv4.ProxyAddress}
+	assert.True(t, NewLocalHeader().Equal(local))
+	assert.False(t, NewLocalHeader().Equal(ipv4))
+}
+
+func TestProxyAddress_Equal(t *testing.T) {
+	tcp := IPv4Address{
+		SourceAddr:      &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+		DestinationAddr: &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+	}
+	udp := IPv4Address{
+		SourceAddr:      &net.UDPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+		DestinationAddr: &net.UDPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+	}
+	otherPort := IPv4Address{
+		SourceAddr:      &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56325},
+		DestinationAddr: &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+	}
+
+	assert.True(t, tcp.Equal(&tcp))
+	assert.False(t, tcp.Equal(udp))
+	assert.False(t, tcp.Equal(otherPort))
+	assert.False(t, tcp.Equal(nil))
+
+	ipv6 := IPv6Address{
+		SourceAddr:      &net.TCPAddr{IP: net.ParseIP("2345:425:2ca1::567:5673:23b5"), Port: 56324},
+		DestinationAddr: &net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443},
+	}
+	assert.True(t, ipv6.Equal(&ipv6))
+	assert.False(t, ipv6.Equal(tcp))
+
+	stream := UnixAddr{
+		SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+		DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unix"},
+	}
+	datagram := UnixAddr{
+		SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unixgram"},
+		DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unixgram"},
+	}
+	assert.True(t, stream.Equal(&stream))
+	assert.False(t, stream.Equal(datagram))
+}
@@ -0,0 +1,95 @@
+package haproxy
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"unicode/utf8"
+)
+
+// ALPN returns the application protocol negotiated with the client, such as
+// "h2" or "http/1.1", from TLVTypeALPN TLV. It returns false if there is no
+// such TLV, so an empty protocol can be told apart from a missing one.
+func (h Header) ALPN() (string, bool) {
+	tlv, ok := h.findTLV(TLVTypeALPN)
+	if !ok {
+		return "", false
+	}
+
+	return string(tlv.Value), true
+}
+
+// Authority returns the host name requested by the client, e.g. with TLS
+// server name indication, from TLVTypeAUTHORITY TLV. It returns false if
+// there is no such TLV, or its value is not a valid UTF-8 string.
+func (h Header) Authority() (string, bool) {
+	tlv, ok := h.findTLV(TLVTypeAUTHORITY)
+	if !ok || !utf8.Valid(tlv.Value) {
+		return "", false
+	}
+
+	return string(tlv.Value), true
+}
+
+// UniqueID returns the opaque identifier of the connection generated by the
+// proxy from TLVTypeUNIQUEID TLV. The returned slice refers to the TLV value.
+func (h Header) UniqueID() ([]byte, bool) {
+	tlv, ok := h.findTLV(TLVTypeUNIQUEID)
+	if !ok {
+		return nil, false
+	}
+
+	return tlv.Value, true
+}
+
+// AddUniqueID appends TLVTypeUNIQUEID TLV with the given identifier, which
+// must be at most MaxUniqueIDLength bytes long.
+func (h *Header) AddUniqueID(id []byte) error {
+	return h.AddTLV(TLVTypeUNIQUEID, id)
+}
+
+// NetNS returns the name of the network namespace the connection was received
+// in from TLVTypeNETNS TLV. The name may be terminated with NUL byte, which is
+// trimmed along with anything that follows it.
+func (h Header) NetNS() (string, bool) {
+	tlv, ok := h.findTLV(TLVTypeNETNS)
+	if !ok {
+		return "", false
+	}
+
+	name := tlv.Value
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+
+	return string(name), true
+}
+
+// SetNetNS sets the value of TLVTypeNETNS TLV to the given namespace name,
+// replacing the existing one.
+func (h *Header) SetNetNS(name string) error {
+	return h.SetTLV(TLVTypeNETNS, []byte(name))
+}
+
+// AuthorityHostPort combines the host name from TLVTypeAUTHORITY TLV with
+// the destination port into a "host:port" string. It returns false if there
+// is no valid authority TLV, or the header has no destination port.
+func (h Header) AuthorityHostPort() (string, bool) {
+	authority, ok := h.Authority()
+	if !ok || h.ProxyAddress == nil {
+		return "", false
+	}
+
+	var port int
+	_, destination := h.ProxyAddress.getAddresses()
+	switch addr := destination.(type) {
+	case *net.TCPAddr:
+		port = addr.Port
+	case *net.UDPAddr:
+		port = addr.Port
+	default:
+		return "", false
+	}
+
+	return net.JoinHostPort(authority, strconv.Itoa(port)), true
+}
@@ -0,0 +1,136 @@
+package haproxy
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader_ALPN(t *testing.T) {
+	header := Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[0].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeAUTHORITY, []byte("example.com")}, {TLVTypeALPN, []byte("h2")}},
+	}
+
+	protocol, ok := header.ALPN()
+	assert.True(t, ok)
+	assert.Equal(t, "h2", protocol)
+
+	header.TLVs = header.TLVs[:1]
+	_, ok = header.ALPN()
+	assert.False(t, ok)
+}
+
+func TestHeader_Authority(t *testing.T) {
+	// Header of a TLS client connecting with SNI "example.com", in the form
+	// HAProxy writes for "send-proxy-v2 proxy-v2-options authority,crc32c":
+	// CRC32C TLV goes first, followed by the authority taken from SNI. The
+	// bytes are written out by hand after HAProxy's make_proxy_line_v2, so
+	// they are checked independently of the encoder of this package.
+	data := []byte{
+		0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A, // Signature
+		0x21, 0x11, 0x00, 0x21, // PROXY, TCP over IPv4, length 33
+		0xC0, 0xA8, 0x00, 0x01, 0xC0, 0xA8, 0x00, 0x0B, 0xDC, 0x04, 0x01, 0xBB, // Addresses and ports
+		0x03, 0x00, 0x04, 0xCC, 0x5B, 0xC6, 0x6B, // CRC32C TLV
+		0x02, 0x00, 0x0B, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm', // Authority TLV
+	}
+
+	var header Header
+	n, err := header.ReadFromWithOptions(bytes.NewReader(data), ReadOptions{VerifyChecksum: true})
+	assert.Nil(t, err)
+	assert.Equal(t, len(data), int(n))
+
+	authority, ok := header.Authority()
+	assert.True(t, ok)
+	assert.Equal(t, "example.com", authority)
+
+	header.TLVs = []TLV{{TLVTypeAUTHORITY, []byte{0xff, 0xfe}}}
+	_, ok = header.Authority()
+	assert.False(t, ok)
+
+	_, ok = header.AuthorityHostPort()
+	assert.False(t, ok)
+
+	header.TLVs = nil
+	_, ok = header.Authority()
+	assert.False(t, ok)
+}
+
+func TestHeader_AuthorityHostPort(t *testing.T) {
+	header := Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[0].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeAUTHORITY, []byte("example.com")}},
+	}
+
+	hostPort, ok := header.AuthorityHostPort()
+	assert.True(t, ok)
+	assert.Equal(t, "example.com:1338", hostPort)
+
+	header.TLVs = nil
+	_, ok = header.AuthorityHostPort()
+	assert.False(t, ok)
+
+	header = Header{
+		Command: CommandPROXY,
+		ProxyAddress: &UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+			DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unix"},
+		},
+		TLVs: []TLV{{TLVTypeAUTHORITY, []byte("example.com")}},
+	}
+
+	_, ok = header.AuthorityHostPort()
+	assert.False(t, ok)
+}
+
+func TestHeader_UniqueID(t *testing.T) {
+	header := Header{Command: CommandPROXY, ProxyAddress: headers[0].ProxyAddress}
+	_, ok := header.UniqueID()
+	assert.False(t, ok)
+
+	assert.Nil(t, header.AddUniqueID([]byte("request-42")))
+	assert.NotNil(t, header.AddUniqueID(make([]byte, MaxUniqueIDLength+1)))
+	assert.Len(t, header.TLVs, 1)
+
+	var decoded Header
+	assert.Nil(t, decoded.UnmarshalBinary(MustEncode(&header)))
+
+	id, ok := decoded.UniqueID()
+	assert.True(t, ok)
+	assert.Equal(t, []byte("request-42"), id)
+
+	// Too long identifier must not be written
+	header.TLVs = []TLV{{TLVTypeUNIQUEID, make([]byte, MaxUniqueIDLength+1)}}
+	buffer := &bytes.Buffer{}
+	_, err := header.WriteTo(buffer)
+	assert.NotNil(t, err)
+
+	_, err = header.Buffers()
+	assert.NotNil(t, err)
+}
+
+func TestHeader_NetNS(t *testing.T) {
+	header := Header{Command: CommandPROXY, ProxyAddress: headers[0].ProxyAddress}
+	_, ok := header.NetNS()
+	assert.False(t, ok)
+
+	assert.Nil(t, header.SetNetNS("tenant-a"))
+	assert.Nil(t, header.SetNetNS("tenant-b"))
+	assert.Len(t, header.TLVs, 1)
+
+	var decoded Header
+	assert.Nil(t, decoded.UnmarshalBinary(MustEncode(&header)))
+
+	name, ok := decoded.NetNS()
+	assert.True(t, ok)
+	assert.Equal(t, "tenant-b", name)
+
+	header.TLVs = []TLV{{TLVTypeNETNS, []byte("tenant-c\x00\x00\x00")}}
+	name, ok = header.NetNS()
+	assert.True(t, ok)
+	assert.Equal(t, "tenant-c", name)
+}
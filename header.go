@@ -1,17 +1,97 @@
 package haproxy
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net"
+	"strings"
+	"sync"
 )
 
-var ProtocolSignature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+// protocolSignature starts every version 2 header. It is a constant, so that
+// parsing can not be broken by modifying ProtocolSignature.
+const protocolSignature = "\r\n\r\n\x00\r\nQUIT\n"
+
+// ProtocolSignature is the signature of version 2 headers.
+//
+// Deprecated: the slice can be modified by any importer, which is never
+// correct, and is not used by the package itself. Use Signature instead.
+var ProtocolSignature = Signature()
+
+// Signature returns a copy of the signature of version 2 headers.
+func Signature() []byte {
+	return []byte(protocolSignature)
+}
 
 type Header struct {
-	Command      Command
+	Command Command
+
+	// ProxyAddress holds addresses of the original connection. Addresses
+	// sent with LOCAL headers are informational only, so they are skipped
+	// when reading and ProxyAddress is left nil.
 	ProxyAddress ProxyAddress
+
+	// TLVs contains extensions that follow the addresses, in the order they
+	// appear on the wire.
+	TLVs []TLV
+
+	// Unsupported is set when reading with ReadOptions.AllowUnsupportedProtocol
+	// if the header carried an address family and transport protocol this
+	// package can not decode, and its body was consumed without parsing it.
+	// It tells such headers apart from the ones that carry no addresses at
+	// all, like "PROXY UNKNOWN". It is ignored when writing.
+	Unsupported bool
+}
+
+// addressLengths contains sizes of address blocks (without TLVs) for every
+// supported protocol.
+var addressLengths = map[ProtocolByte]AddressLength{
+	{AddressFamilyINET, TransportProtocolSTREAM}:  ipv4AddressLength,
+	{AddressFamilyINET, TransportProtocolDGRAM}:   ipv4AddressLength,
+	{AddressFamilyINET6, TransportProtocolSTREAM}: ipv6AddressLength,
+	{AddressFamilyINET6, TransportProtocolDGRAM}:  ipv6AddressLength,
+	{AddressFamilyUNIX, TransportProtocolSTREAM}:  unixAddressLength,
+	{AddressFamilyUNIX, TransportProtocolDGRAM}:   unixAddressLength,
+}
+
+// ErrUnexpectedTLVs is returned when the header contains TLVs, but
+// ReadOptions.RejectTLVs is set.
+var ErrUnexpectedTLVs = errors.New("header contains TLVs, but they are not allowed")
+
+// ErrMissingAddress is returned when writing a PROXY header without
+// ProxyAddress. Only LOCAL headers may be written without addresses.
+var ErrMissingAddress = errors.New("PROXY header must have an address, only LOCAL headers may be written without it")
+
+// ErrUnspecifiedProtocol is returned when a PROXY header has unspecified
+// address family or transport protocol, but ReadOptions.StrictMode is set.
+var ErrUnspecifiedProtocol = errors.New("PROXY header has unspecified address family or transport protocol")
+
+// ErrIncompleteHeader matches IncompleteHeaderError with errors.Is.
+var ErrIncompleteHeader = errors.New("incomplete header")
+
+// IncompleteHeaderError is returned when the data ends before the end of the
+// header, which is not necessarily malformed, so the caller may wait for more
+// data. Read is the number of bytes read, and Expected is the number of bytes
+// the parser needed at that point. The length of a version 1 header is not
+// known in advance, so it only needs one more byte than was read.
+//
+// errors.Is reports that IncompleteHeaderError is ErrIncompleteHeader.
+type IncompleteHeaderError struct {
+	Read     int
+	Expected int
+}
+
+func (i IncompleteHeaderError) Error() string {
+	return fmt.Sprintf("incomplete header: read %d bytes, but expected %d", i.Read, i.Expected)
+}
+
+func (i IncompleteHeaderError) Is(target error) bool {
+	return target == ErrIncompleteHeader
 }
 
 type ProxyProtocolError struct {
@@ -23,6 +103,37 @@ func (p ProxyProtocolError) Error() string {
 	return "unexpected bytes in the beginning of header, there was no protocol header present"
 }
 
+// UnsupportedVersionError is returned when the header carries a protocol
+// version other than ProtocolVersion.
+type UnsupportedVersionError struct {
+	Version byte
+}
+
+func (v UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("unsupported protocol version: expected %x, but got %x", ProtocolVersion, v.Version)
+}
+
+// UnsupportedCommandError is returned when the header carries a command other
+// than CommandLOCAL and CommandPROXY.
+type UnsupportedCommandError struct {
+	Command Command
+}
+
+func (c UnsupportedCommandError) Error() string {
+	return fmt.Sprintf("unsupported command: expected either 0x0 or 0x1, but got %s", c.Command)
+}
+
+// ChecksumError is returned when the CRC32c checksum transmitted in
+// TLVTypeCRC32C TLV does not match the one computed over the header.
+type ChecksumError struct {
+	Transmitted uint32
+	Computed    uint32
+}
+
+func (c ChecksumError) Error() string {
+	return fmt.Sprintf("header checksum mismatch: transmitted %08x, but computed %08x", c.Transmitted, c.Computed)
+}
+
 type TransportProtocolError struct {
 	TransportProtocol TransportProtocol
 	AddressFamily     AddressFamily
@@ -31,70 +142,291 @@ type TransportProtocolError struct {
 
 func (p TransportProtocolError) Error() string {
 	return fmt.Sprintf(
-		"unsupported protocol %x with address type %x (length %d)",
+		"unsupported protocol %s with address type %s (length %d)",
 		p.TransportProtocol, p.AddressFamily, p.AddressLength,
 	)
 }
 
-func (h *Header) ReadFrom(r io.Reader) (m int64, err error) {
-	signature := make([]byte, 12)
-	n, err := r.Read(signature)
-	m += int64(n)
+// HeaderTooLongError is returned when the length declared by a header exceeds
+// ReadOptions.MaxHeaderLength.
+type HeaderTooLongError struct {
+	Length    AddressLength
+	MaxLength AddressLength
+}
+
+func (e HeaderTooLongError) Error() string {
+	return fmt.Sprintf("header declares length %d, but at most %d is allowed", e.Length, e.MaxLength)
+}
+
+// LengthMismatchError is returned when the declared length is too short to
+// hold the addresses of the declared address family. Anything beyond
+// ExpectedLength is TLVs, so longer lengths are valid.
+type LengthMismatchError struct {
+	AddressFamily     AddressFamily
+	TransportProtocol TransportProtocol
+	Length            AddressLength
+	ExpectedLength    AddressLength
+}
+
+func (l LengthMismatchError) Error() string {
+	return fmt.Sprintf(
+		"address length %d is too short for protocol %s/%s, expected at least %d",
+		l.Length, l.AddressFamily, l.TransportProtocol, l.ExpectedLength,
+	)
+}
+
+// ReadHeader reads either a version 1 or a version 2 header from r, detecting
+// the version by the first bytes. The bytes used for detection are replayed to
+// the corresponding parser, and nothing past the end of the header is read,
+// so r is left positioned at the first byte of application data.
+//
+// If the data starts with neither the version 2 signature nor "PROXY ",
+// a ProxyProtocolError carrying the observed prefix is returned.
+func ReadHeader(r io.Reader) (*Header, error) {
+	prefix := make([]byte, len(V1Signature))
+	n, err := io.ReadFull(r, prefix)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, &IncompleteHeaderError{Read: n, Expected: len(prefix)}
+	}
+
 	if err != nil {
-		return m, err
+		return nil, err
+	}
+
+	var header Header
+	replay := io.MultiReader(bytes.NewReader(prefix[:n]), r)
+
+	switch {
+	case bytes.Equal(prefix, V1Signature):
+		_, err = header.ReadFromV1(replay)
+	case string(prefix) == protocolSignature[:len(prefix)]:
+		_, err = header.ReadFrom(replay)
+	default:
+		return nil, &ProxyProtocolError{Signature(), prefix}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &header, nil
+}
+
+// ReadHeaderFrom reads a header like ReadHeader does, but reads r through
+// a bufio.Reader, which saves system calls when r is a connection. The
+// returned reader is positioned right after the header, and yields the bytes
+// that were buffered but not consumed by the parser before reading from r
+// again, so no application data is lost. If r already is a *bufio.Reader,
+// it is used as is and returned.
+func ReadHeaderFrom(r io.Reader) (*Header, io.Reader, error) {
+	reader, ok := r.(*bufio.Reader)
+	if !ok {
+		reader = bufio.NewReader(r)
 	}
 
-	if !bytes.Equal(signature, ProtocolSignature) {
-		return m, &ProxyProtocolError{ProtocolSignature, signature}
+	header, err := ReadHeader(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return header, reader, nil
+}
+
+// SignatureLength is the number of bytes IsProxyHeader needs to recognize
+// a header of any version. It is the length of the version 2 signature, while
+// the version 1 one takes only 6 bytes.
+const SignatureLength = len(protocolSignature)
+
+// IsProxyHeader reports whether peek starts with the signature of a version 1
+// or a version 2 header, without parsing the rest of it, so that listeners
+// serving both proxied and direct connections on the same port can route
+// them after peeking at the first bytes, e.g. with bufio.Reader.Peek. Both
+// results are false if peek is too short to tell, i.e. shorter than 6 bytes
+// for version 1 or SignatureLength bytes for version 2.
+func IsProxyHeader(peek []byte) (isV1 bool, isV2 bool) {
+	isV1 = bytes.HasPrefix(peek, V1Signature)
+	isV2 = len(peek) >= len(protocolSignature) && string(peek[:len(protocolSignature)]) == protocolSignature
+	return isV1, isV2
+}
+
+// ParseHeader parses either a version 1 or a version 2 header in the
+// beginning of data, and returns it along with the number of bytes it takes.
+// Bytes that follow the header are ignored. If data ends before the header
+// does, ErrIncompleteHeader is returned, so that the caller can wait for more
+// data instead of rejecting it. The error is an IncompleteHeaderError, which
+// can be checked with errors.Is.
+func ParseHeader(data []byte) (*Header, int, error) {
+	// Too short data can only be told apart from garbage by its prefix
+	if len(data) < len(V1Signature) &&
+		!bytes.HasPrefix(V1Signature, data) && !strings.HasPrefix(protocolSignature, string(data)) {
+		return nil, 0, &ProxyProtocolError{Signature(), data}
+	}
+
+	reader := bytes.NewReader(data)
+	header, err := ReadHeader(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return header, len(data) - reader.Len(), nil
+}
+
+// Reset clears all fields of the header, so that it can be reused.
+func (h *Header) Reset() {
+	*h = Header{}
+}
+
+// ReadFrom reads a version 2 header from r. All fields of the header are
+// overwritten, so a single Header value can be reused for many reads without
+// keeping anything from the previous one. If an error is returned, fields
+// hold whatever was decoded before it.
+func (h *Header) ReadFrom(r io.Reader) (m int64, err error) {
+	return h.ReadFromWithOptions(r, ReadOptions{})
+}
+
+// ReadFromWithOptions reads the header like ReadFrom does, but allows to
+// enable additional checks using ReadOptions.
+func (h *Header) ReadFromWithOptions(r io.Reader, opts ReadOptions) (m int64, err error) {
+	// Keep a copy of the received header, since checksum covers all of its bytes
+	var raw *bytes.Buffer
+	if opts.VerifyChecksum {
+		raw = &bytes.Buffer{}
+		r = io.TeeReader(r, raw)
 	}
 
-	// Read protocol version and command, combined in a single byte
-	var version VersionByte
-	k, err := version.ReadFrom(r)
-	m += k
+	m, err = h.readFrom(r, opts)
 	if err != nil {
 		return m, err
 	}
 
-	// As of this specification, it must always be sent as \x2 and the receiver must only accept this value.
-	if version.ProtocolVersion != ProtocolVersion {
-		return m, fmt.Errorf("unsupported protocol version: expected %x, but got %x", ProtocolVersion, version.ProtocolVersion)
+	if opts.VerifyChecksum {
+		err = h.verifyChecksum(raw.Bytes())
+		if err != nil {
+			return m, err
+		}
 	}
 
-	// Other values are unassigned and must not be emitted by senders. Receivers
-	// must drop connections presenting unexpected values here.
-	if version.Command != CommandLOCAL && version.Command != CommandPROXY {
-		return m, fmt.Errorf("unsupported command: expected either 0x0 or 0x1, but got %x", version.Command)
+	if opts.AddressRewriter != nil && h.ProxyAddress != nil {
+		address, err := WrapAddress(opts.AddressRewriter(h.ProxyAddress.getAddresses()))
+		if err != nil {
+			return m, fmt.Errorf("unable to use rewritten addresses: %w", err)
+		}
+
+		// WrapAddress treats IPv4-mapped addresses as IPv4, but masking an
+		// IPv6 header must not change its address family
+		if ipv4, ok := address.(*IPv4Address); ok {
+			if _, ok := h.ProxyAddress.(*IPv6Address); ok {
+				address = &IPv6Address{SourceAddr: ipv4.SourceAddr, DestinationAddr: ipv4.DestinationAddr}
+			}
+		}
+
+		h.ProxyAddress = address
+	}
+
+	return
+}
+
+// prefixPool holds buffers for the fixed 16-byte part of the header, which is
+// parsed right away and never referenced afterwards.
+var prefixPool = sync.Pool{
+	New: func() interface{} {
+		return new([16]byte)
+	},
+}
+
+// appendPool holds buffers for serializing headers that are written as a
+// whole, so that their contents are never referenced after writing.
+var appendPool = sync.Pool{
+	New: func() interface{} {
+		buffer := make([]byte, 0, 64)
+		return &buffer
+	},
+}
+
+func (h *Header) readFrom(r io.Reader, opts ReadOptions) (m int64, err error) {
+	h.Reset()
+
+	prefix := prefixPool.Get().(*[16]byte)
+	defer prefixPool.Put(prefix)
+
+	// Short reads are reported along with the number of bytes the parser was
+	// waiting for at that point
+	expected := len(protocolSignature)
+	defer func() {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = &IncompleteHeaderError{Read: int(m), Expected: expected}
+		}
+	}()
+
+	// The fixed part is read at once. If it is cut short, whatever part of it
+	// did arrive is still validated, so that garbage is reported as such and
+	// not as an incomplete header
+	n, readErr := io.ReadFull(r, prefix[:])
+	m += int64(n)
+
+	if n >= 12 && string(prefix[:12]) != protocolSignature {
+		return m, &ProxyProtocolError{Signature(), append([]byte{}, prefix[:12]...)}
 	}
 
-	h.Command = version.Command
+	// Protocol version and command are combined in a single byte
+	if n >= 13 {
+		version := VersionByte{
+			ProtocolVersion: prefix[12] >> 4,
+			Command:         Command(prefix[12] & 0b1111),
+		}
+
+		err = version.Validate()
+		if err != nil {
+			return m, err
+		}
+
+		h.Command = version.Command
+	}
 
 	var protocol ProtocolByte
-	k, err = protocol.ReadFrom(r)
-	m += k
-	if err != nil {
-		return
+	if n >= 14 {
+		protocol = ProtocolByte{
+			AddressFamily:     AddressFamily(prefix[13] >> 4),
+			TransportProtocol: TransportProtocol(prefix[13] & 0b1111),
+		}
+
+		err = ValidateProtocolByte(protocol)
+		if err != nil {
+			return m, err
+		}
+
+		if opts.StrictMode && h.Command == CommandPROXY &&
+			(protocol.AddressFamily == AddressFamilyUNSPEC || protocol.TransportProtocol == TransportProtocolUNSPEC) {
+			return m, ErrUnspecifiedProtocol
+		}
 	}
 
-	// Other values are unspecified and must not be emitted in version 2 of the
-	// protocol and must be rejected as invalid by receivers.
-	if protocol.AddressFamily != AddressFamilyUNSPEC && protocol.AddressFamily != AddressFamilyINET &&
-		protocol.AddressFamily != AddressFamilyINET6 && protocol.AddressFamily != AddressFamilyUNIX {
-		return m, fmt.Errorf("unsupported address family: expected 0x0 - 0x3, but got %x", protocol.AddressFamily)
+	if readErr != nil {
+		// Report the field the parser would have been waiting for, if the
+		// prefix was read field by field
+		switch {
+		case n < 12:
+			expected = 12
+		case n < 14:
+			expected = n + 1
+		default:
+			expected = 16
+		}
+
+		return m, readErr
 	}
 
-	// Other values are unspecified and must not be emitted in version 2 of the
-	// protocol and must be rejected as invalid by receivers.
-	if protocol.TransportProtocol != TransportProtocolUNSPEC && protocol.TransportProtocol != TransportProtocolSTREAM &&
-		protocol.TransportProtocol != TransportProtocolDGRAM {
-		return m, fmt.Errorf("unsupported transport protocol: expected 0x0 - 0x2, but got %x", protocol.TransportProtocol)
+	addressLength := AddressLength(binary.BigEndian.Uint16(prefix[14:16]))
+	expected = 16 + int(addressLength)
+
+	maxLength := opts.MaxHeaderLength
+	if maxLength == 0 {
+		maxLength = DefaultMaxHeaderLength
 	}
 
-	var addressLength AddressLength
-	k, err = addressLength.ReadFrom(r)
-	m += k
-	if err != nil {
-		return
+	// Nothing is allocated for the declared length until it is known to be sane
+	if addressLength > maxLength {
+		return m, &HeaderTooLongError{Length: addressLength, MaxLength: maxLength}
 	}
 
 	// If there is no address data (e.g. in cases when command is LOCAL),
@@ -103,6 +435,49 @@ func (h *Header) ReadFrom(r io.Reader) (m int64, err error) {
 		return
 	}
 
+	// Receivers must ignore addresses of LOCAL headers, but some senders put
+	// them anyway. They are informational only, so they are skipped.
+	if h.Command == CommandLOCAL {
+		k, err := io.CopyN(io.Discard, r, int64(addressLength))
+		return m + k, err
+	}
+
+	fixedLength, supported := addressLengths[protocol]
+	if supported && addressLength < fixedLength {
+		return m, &LengthMismatchError{protocol.AddressFamily, protocol.TransportProtocol, addressLength, fixedLength}
+	}
+
+	if opts.Streaming {
+		// Parsers of the body can not read past the declared length into
+		// application data, whatever they do, and anything they leave unread
+		// is skipped, so that the stream stays aligned
+		body := &io.LimitedReader{R: r, N: int64(addressLength)}
+		k, err := h.readBody(body, protocol, addressLength, fixedLength, opts)
+		if err == nil && body.N > 0 {
+			var skipped int64
+			skipped, err = io.Copy(io.Discard, body)
+			k += skipped
+		}
+
+		return m + k, err
+	}
+
+	// Addresses and TLVs are read with a single call and parsed from memory
+	data := make([]byte, addressLength)
+	n, err = io.ReadFull(r, data)
+	m += int64(n)
+	if err != nil {
+		return m, err
+	}
+
+	_, err = h.readBody(bytes.NewReader(data), protocol, addressLength, fixedLength, opts)
+	return m, err
+}
+
+// readBody reads addresses and TLVs that follow the fixed part of the header.
+func (h *Header) readBody(
+	r io.Reader, protocol ProtocolByte, addressLength, fixedLength AddressLength, opts ReadOptions,
+) (m int64, err error) {
 	switch protocol {
 	// TCP over IPv4
 	case ProtocolByte{AddressFamilyINET, TransportProtocolSTREAM}:
@@ -186,12 +561,12 @@ func (h *Header) ReadFrom(r io.Reader) (m int64, err error) {
 
 		h.ProxyAddress = &UnixAddr{
 			SourceAddr: &net.UnixAddr{
-				Name: string(result.SourceAddr),
-				Net:  "unixpacket",
+				Name: unixPath(result.SourceAddr),
+				Net:  "unix",
 			},
 			DestinationAddr: &net.UnixAddr{
-				Name: string(result.DestinationAddr),
-				Net:  "unixpacket",
+				Name: unixPath(result.DestinationAddr),
+				Net:  "unix",
 			},
 		}
 	// UNIX datagram
@@ -204,74 +579,400 @@ func (h *Header) ReadFrom(r io.Reader) (m int64, err error) {
 
 		h.ProxyAddress = &UnixAddr{
 			SourceAddr: &net.UnixAddr{
-				Name: string(result.SourceAddr),
+				Name: unixPath(result.SourceAddr),
 				Net:  "unixgram",
 			},
 			DestinationAddr: &net.UnixAddr{
-				Name: string(result.DestinationAddr),
+				Name: unixPath(result.DestinationAddr),
 				Net:  "unixgram",
 			},
 		}
-	// If protocol is not supported, read remaining bytes and return an error
+	// If protocol is not supported, skip remaining bytes, since addresses
+	// and TLVs can not be told apart, and return an error unless allowed
 	default:
-		data := make([]byte, addressLength)
-		n, err := r.Read(data)
-		m += int64(n)
+		n, err := io.CopyN(io.Discard, r, int64(addressLength))
+		m += n
 		if err != nil {
 			return m, err
 		}
 
+		if opts.AllowUnsupportedProtocol {
+			h.Unsupported = true
+			return m, nil
+		}
+
 		return m, &TransportProtocolError{protocol.TransportProtocol, protocol.AddressFamily, addressLength}
 	}
 
+	// Everything that follows addresses up to the declared length is TLVs
+	h.TLVs = nil
+	if addressLength > fixedLength {
+		if opts.RejectTLVs {
+			return m, ErrUnexpectedTLVs
+		}
+
+		data := make([]byte, addressLength-fixedLength)
+		n, err := io.ReadFull(r, data)
+		m += int64(n)
+		if err != nil {
+			return m, err
+		}
+
+		h.TLVs, err = parseTLVs(data)
+		if err != nil {
+			return m, err
+		}
+	}
+
 	return
 }
 
 func (h Header) WriteTo(w io.Writer) (m int64, err error) {
-	n, err := w.Write(ProtocolSignature)
-	m += int64(n)
+	return h.WriteToWithOptions(w, WriteOptions{})
+}
+
+// WriteToWithOptions writes the header like WriteTo does, but allows to
+// alter the output using WriteOptions.
+func (h Header) WriteToWithOptions(w io.Writer, opts WriteOptions) (m int64, err error) {
+	buffer := appendPool.Get().(*[]byte)
+	defer appendPool.Put(buffer)
+
+	data, err := h.appendWithOptions((*buffer)[:0], opts)
 	if err != nil {
-		return m, err
+		return 0, err
+	}
+
+	*buffer = data
+	n, err := writeFull(w, data)
+	return int64(n), err
+}
+
+// writeFull writes all of data to w. Writers must return an error along with
+// a short write, but some non-blocking ones do not, which would silently
+// truncate the header, so writing is retried until all bytes are accepted.
+// A writer that accepts nothing without an error gets io.ErrShortWrite.
+func writeFull(w io.Writer, data []byte) (n int, err error) {
+	for n < len(data) {
+		m, err := w.Write(data[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+
+		if m == 0 {
+			return n, io.ErrShortWrite
+		}
 	}
 
-	version := VersionByte{
-		ProtocolVersion: ProtocolVersion,
-		Command:         h.Command,
+	return n, nil
+}
+
+// AppendTo appends the header in version 2 format to dst and returns the
+// extended slice, like WriteTo writes it. Reusing dst for multiple headers
+// allows to serialize them without allocations. If the header cannot be
+// encoded, dst is returned unchanged along with the error.
+func (h Header) AppendTo(dst []byte) ([]byte, error) {
+	return h.appendWithOptions(dst, WriteOptions{})
+}
+
+func (h Header) appendWithOptions(dst []byte, opts WriteOptions) ([]byte, error) {
+	if h.Command == CommandPROXY && h.ProxyAddress == nil {
+		return dst, ErrMissingAddress
 	}
 
-	k, err := version.WriteTo(w)
-	m += k
-	if err != nil {
-		return m, err
+	if opts.Checksum && h.Command == CommandPROXY {
+		return h.appendWithChecksum(dst, opts)
 	}
 
-	k, err = h.ProxyAddress.getSignature().WriteTo(w)
-	m += k
+	version := NewVersionByte(h.Command)
+	err := version.Validate()
 	if err != nil {
-		return
+		return dst, err
+	}
+
+	if opts.ProtocolVersionOverride != 0 {
+		if opts.ProtocolVersionOverride > 0xF {
+			return dst, fmt.Errorf("protocol version override must fit in 4 bits, but got %x", opts.ProtocolVersionOverride)
+		}
+
+		version.ProtocolVersion = opts.ProtocolVersionOverride
+	}
+
+	// LOCAL headers may come without address, which is sent as unspecified
+	signature := ProtocolByte{AddressFamilyUNSPEC, TransportProtocolUNSPEC}
+	if h.ProxyAddress != nil {
+		signature, err = h.ProxyAddress.getSignature()
+		if err != nil {
+			return dst, err
+		}
 	}
 
 	// We should write address data only if command is PROXY.
 	// In case if command is LOCAL, address length is written as zero, and no address follows it
+	var length AddressLength
 	if h.Command == CommandPROXY {
-		k, err = h.ProxyAddress.getLength().WriteTo(w)
-		m += k
+		length, err = h.bodyLength()
 		if err != nil {
-			return
+			return dst, err
 		}
+	}
+
+	start := len(dst)
+	dst = append(dst, protocolSignature...)
+	dst = append(dst,
+		version.ProtocolVersion<<4|byte(version.Command),
+		byte(signature.AddressFamily<<4)|byte(signature.TransportProtocol),
+		byte(length>>8), byte(length),
+	)
 
-		k, err = h.ProxyAddress.WriteTo(w)
-		m += k
+	if h.Command != CommandPROXY {
+		return dst, nil
+	}
+
+	dst, err = h.ProxyAddress.appendTo(dst)
+	if err != nil {
+		return dst[:start], err
+	}
+
+	for _, tlv := range h.TLVs {
+		dst, err = tlv.appendTo(dst)
 		if err != nil {
-			return m, err
+			return dst[:start], err
 		}
-	} else {
-		k, err = AddressLength(0).WriteTo(w)
-		m += k
+	}
+
+	return dst, nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler and returns the header in
+// version 2 format, as written by WriteTo.
+func (h Header) MarshalBinary() ([]byte, error) {
+	return h.AppendTo(nil)
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. The data must hold
+// exactly one version 2 header, any bytes that follow it are an error. The
+// header is left unchanged if the data cannot be decoded.
+func (h *Header) UnmarshalBinary(data []byte) error {
+	reader := bytes.NewReader(data)
+
+	var decoded Header
+	_, err := decoded.ReadFrom(reader)
+	if err != nil {
+		return err
+	}
+
+	if reader.Len() > 0 {
+		return fmt.Errorf("unexpected %d bytes after the end of header", reader.Len())
+	}
+
+	*h = decoded
+	return nil
+}
+
+// MustEncode returns serialized header and panics if it cannot be encoded.
+// Like regexp.MustCompile, it is intended for tests and initialization of
+// package-level variables, not for handling data at runtime.
+func MustEncode(h *Header) []byte {
+	data, err := h.AppendTo(nil)
+	if err != nil {
+		panic("haproxy: unable to encode header: " + err.Error())
+	}
+
+	return data
+}
+
+// bodyLength returns the value of the length field, which covers addresses
+// and all TLVs that follow them.
+func (h Header) bodyLength() (AddressLength, error) {
+	length := 0
+	if h.ProxyAddress != nil {
+		length += int(h.ProxyAddress.getLength())
+	}
+
+	for _, tlv := range h.TLVs {
+		err := tlv.validate()
 		if err != nil {
-			return
+			return 0, err
 		}
+
+		length += 3 + len(tlv.Value)
 	}
 
-	return
+	if length > math.MaxUint16 {
+		return 0, fmt.Errorf("addresses and TLVs take %d bytes, which does not fit in the length field", length)
+	}
+
+	return AddressLength(length), nil
+}
+
+// RequiredMaxLength returns the value of the length field this header is
+// written with, i.e. the smallest ReadOptions.MaxHeaderLength that a receiver
+// may set and still accept this header. LOCAL headers are written with zero
+// length. Note that WriteOptions.Checksum adds a 7-byte TLV, unless the
+// header already has one.
+//
+// An error is returned if the length cannot be determined, because the
+// header has no address, its address is unsupported, or its TLVs are
+// invalid or do not fit in the length field. Addresses themselves are not
+// validated, so WriteTo may still fail, e.g. on nil IPs.
+func (h Header) RequiredMaxLength() (AddressLength, error) {
+	if h.Command != CommandPROXY {
+		return 0, nil
+	}
+
+	if h.ProxyAddress == nil {
+		return 0, ErrMissingAddress
+	}
+
+	_, err := h.ProxyAddress.getSignature()
+	if err != nil {
+		return 0, err
+	}
+
+	return h.bodyLength()
+}
+
+// EncodedLen returns the number of bytes WriteTo writes for this header in
+// version 2 format without encoding it, e.g. to size buffers or to check that
+// a header fits in a datagram. Headers that RequiredMaxLength rejects are an
+// error here as well.
+func (h Header) EncodedLen() (int, error) {
+	length, err := h.RequiredMaxLength()
+	if err != nil {
+		return 0, err
+	}
+
+	return 16 + int(length), nil
+}
+
+// SelfConsistent encodes the header, decodes it back and checks that nothing
+// was lost on the way, returning an error that describes the divergence.
+// It is meant to be used in tests to catch addresses that cannot be
+// represented on the wire exactly, such as IPv6 zones.
+func (h Header) SelfConsistent() error {
+	buffer := &bytes.Buffer{}
+	_, err := h.WriteTo(buffer)
+	if err != nil {
+		return fmt.Errorf("unable to encode header: %w", err)
+	}
+
+	var decoded Header
+	_, err = decoded.ReadFrom(buffer)
+	if err != nil {
+		return fmt.Errorf("unable to decode header: %w", err)
+	}
+
+	if decoded.Command != h.Command {
+		return fmt.Errorf("command %s was decoded as %s", h.Command, decoded.Command)
+	}
+
+	// Addresses are not sent with LOCAL command
+	if h.Command == CommandPROXY && !equalProxyAddress(h.ProxyAddress, decoded.ProxyAddress) {
+		return fmt.Errorf(
+			"address %s was decoded as %s", describeProxyAddress(h.ProxyAddress), describeProxyAddress(decoded.ProxyAddress),
+		)
+	}
+
+	if !equalTLVs(h.TLVs, decoded.TLVs) {
+		return fmt.Errorf("%d TLVs were decoded as %d TLVs with different contents", len(h.TLVs), len(decoded.TLVs))
+	}
+
+	return nil
+}
+
+func describeProxyAddress(a ProxyAddress) string {
+	if a == nil {
+		return "<nil>"
+	}
+
+	source, destination := a.getAddresses()
+	return fmt.Sprintf("%s %s -> %s %s", source.Network(), source, destination.Network(), destination)
+}
+
+// signature returns the protocol byte the header is sent with. It is UNSPEC
+// for LOCAL headers, headers without address and addresses that can not be
+// sent.
+func (h Header) signature() ProtocolByte {
+	if h.Command != CommandPROXY || h.ProxyAddress == nil {
+		return ProtocolByte{AddressFamilyUNSPEC, TransportProtocolUNSPEC}
+	}
+
+	signature, err := h.ProxyAddress.getSignature()
+	if err != nil {
+		return ProtocolByte{AddressFamilyUNSPEC, TransportProtocolUNSPEC}
+	}
+
+	return signature
+}
+
+// UnknownAddresses reports whether the header is a PROXY one, but carries no
+// addresses, like "PROXY UNKNOWN" version 1 headers do. Such connections were
+// relayed by the proxy, which did not know the original addresses, so
+// receivers must use the real connection endpoints instead.
+func (h Header) UnknownAddresses() bool {
+	return h.Command == CommandPROXY && h.ProxyAddress == nil
+}
+
+// AddressFamily returns the address family of the original connection, such
+// as AddressFamilyINET for IPv4, without the need to inspect ProxyAddress.
+// It is AddressFamilyUNSPEC for LOCAL headers and headers without address.
+func (h Header) AddressFamily() AddressFamily {
+	return h.signature().AddressFamily
+}
+
+// TransportProtocol returns the transport protocol of the original
+// connection, such as TransportProtocolSTREAM for TCP. It is
+// TransportProtocolUNSPEC for LOCAL headers and headers without address.
+func (h Header) TransportProtocol() TransportProtocol {
+	return h.signature().TransportProtocol
+}
+
+// String returns a human-readable representation of the header, such as
+// "PROXY TCP4 127.0.0.1:42446 -> 127.0.0.1:1338". Headers without address
+// are described as "LOCAL" or "PROXY UNKNOWN" depending on the command.
+func (h Header) String() string {
+	command := h.Command.String()
+	if h.ProxyAddress == nil {
+		if h.Command == CommandLOCAL {
+			return command
+		}
+
+		return command + " UNKNOWN"
+	}
+
+	protocol := "UNKNOWN"
+	if signature, err := h.ProxyAddress.getSignature(); err == nil {
+		protocol = describeProtocolByte(signature)
+	}
+
+	source, destination := h.ProxyAddress.getAddresses()
+	return fmt.Sprintf("%s %s %s -> %s", command, protocol, source, destination)
+}
+
+func describeProtocolByte(p ProtocolByte) string {
+	var transport string
+	switch p.TransportProtocol {
+	case TransportProtocolSTREAM:
+		transport = "TCP"
+	case TransportProtocolDGRAM:
+		transport = "UDP"
+	default:
+		return "UNKNOWN"
+	}
+
+	switch p.AddressFamily {
+	case AddressFamilyINET:
+		return transport + "4"
+	case AddressFamilyINET6:
+		return transport + "6"
+	case AddressFamilyUNIX:
+		if p.TransportProtocol == TransportProtocolDGRAM {
+			return "UNIX_DGRAM"
+		}
+
+		return "UNIX_STREAM"
+	default:
+		return "UNKNOWN"
+	}
 }
@@ -2,8 +2,12 @@ package haproxy
 
 import (
 	"bytes"
+	"errors"
+	"io"
 	"net"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -57,9 +61,9 @@ var decodeTests = []func(t *testing.T, header *Header, read int, err error){
 		assert.Equal(t, &net.UDPAddr{IP: destination, Port: 443}, addr.DestinationAddr)
 	},
 
-	// Header with broken signature
+	// Header with broken signature, detected after the fixed part is read
 	func(t *testing.T, header *Header, read int, err error) {
-		assert.Equal(t, 12, read)
+		assert.Equal(t, 16, read)
 		assert.NotNil(t, err)
 		assert.IsType(t, &ProxyProtocolError{}, err)
 	},
@@ -126,3 +130,1146 @@ func TestHeader_WriteTo(t *testing.T) {
 		assert.Equal(t, expected, buffer.Bytes())
 	}
 }
+
+func TestHeader_WriteToWithOptions_ProtocolVersionOverride(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	_, err := headers[0].WriteToWithOptions(buffer, WriteOptions{ProtocolVersionOverride: 0x3})
+	assert.Nil(t, err)
+
+	expected := append([]byte{}, expectedEncodedHeaders[0]...)
+	expected[12] = 0x31
+	assert.Equal(t, expected, buffer.Bytes())
+
+	// Receivers must reject any version other than 2
+	var header Header
+	_, err = header.ReadFrom(buffer)
+	assert.NotNil(t, err)
+
+	_, err = headers[0].WriteToWithOptions(&bytes.Buffer{}, WriteOptions{ProtocolVersionOverride: 0x10})
+	assert.NotNil(t, err)
+}
+
+func TestHeader_WriteTo_UnsupportedCommand(t *testing.T) {
+	header := &Header{Command: Command(0x05)}
+	buffer := &bytes.Buffer{}
+	n, err := header.WriteTo(buffer)
+	assert.Equal(t, &UnsupportedCommandError{Command(0x05)}, err)
+	assert.Equal(t, int64(0), n)
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestReadHeader(t *testing.T) {
+	v1 := "PROXY TCP4 127.0.0.1 127.0.0.1 42446 1338\r\n"
+	streams := [][]byte{
+		append(append([]byte{}, encodedHeaders[0]...), "payload"...),
+		append([]byte(v1), "payload"...),
+	}
+
+	for _, data := range streams {
+		reader := bytes.NewReader(data)
+		header, err := ReadHeader(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, CommandPROXY, header.Command)
+
+		addr := header.ProxyAddress.(*IPv4Address)
+		assert.Equal(t, &net.TCPAddr{IP: []byte{127, 0, 0, 1}, Port: 42446}, addr.SourceAddr)
+		assert.Equal(t, &net.TCPAddr{IP: []byte{127, 0, 0, 1}, Port: 1338}, addr.DestinationAddr)
+
+		rest := make([]byte, reader.Len())
+		_, _ = reader.Read(rest)
+		assert.Equal(t, "payload", string(rest))
+	}
+}
+
+func TestReadHeader_NoSignature(t *testing.T) {
+	_, err := ReadHeader(bytes.NewReader([]byte("GET / HTTP/1.1\r\n")))
+	assert.IsType(t, &ProxyProtocolError{}, err)
+	assert.Equal(t, []byte("GET / "), err.(*ProxyProtocolError).Found)
+}
+
+func TestHeader_SelfConsistent(t *testing.T) {
+	for _, header := range headers {
+		assert.Nil(t, header.SelfConsistent())
+	}
+
+	// IPv6 zone cannot be represented in the header
+	header := Header{
+		Command: CommandPROXY,
+		ProxyAddress: &IPv6Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("fe80::1"), Port: 42446, Zone: "eth0"},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("fe80::2"), Port: 1338},
+		},
+	}
+
+	assert.NotNil(t, header.SelfConsistent())
+}
+
+func TestHeader_ReadFromWithOptions_AddressRewriter(t *testing.T) {
+	opts := ReadOptions{
+		AddressRewriter: func(src, dst net.Addr) (net.Addr, net.Addr) {
+			masked := *src.(*net.TCPAddr)
+			masked.IP = net.IPv4(127, 0, 0, 0).To4()
+			return &masked, dst
+		},
+	}
+
+	var header Header
+	_, err := header.ReadFromWithOptions(bytes.NewReader(encodedHeaders[0]), opts)
+	assert.Nil(t, err)
+
+	addr := header.ProxyAddress.(*IPv4Address)
+	assert.Equal(t, &net.TCPAddr{IP: []byte{127, 0, 0, 0}, Port: 42446}, addr.SourceAddr)
+	assert.Equal(t, &net.TCPAddr{IP: []byte{127, 0, 0, 1}, Port: 1338}, addr.DestinationAddr)
+}
+
+func TestHeader_ReadFromWithOptions_AddressRewriter_Family(t *testing.T) {
+	mapped := net.ParseIP("::ffff:10.0.0.1")
+	opts := ReadOptions{
+		AddressRewriter: func(src, dst net.Addr) (net.Addr, net.Addr) {
+			masked := *src.(*net.UDPAddr)
+			masked.IP = mapped
+			return &masked, &net.UDPAddr{IP: mapped, Port: 443}
+		},
+	}
+
+	// IPv4-mapped addresses keep IPv6 header in IPv6 family
+	var header Header
+	_, err := header.ReadFromWithOptions(bytes.NewReader(encodedHeaders[1]), opts)
+	assert.Nil(t, err)
+	assert.IsType(t, &IPv6Address{}, header.ProxyAddress)
+	assert.Equal(t, AddressFamilyINET6, header.AddressFamily())
+	assert.Equal(t, byte(0x22), MustEncode(&header)[13])
+
+	// IPv6 addresses can only be represented in IPv6 family
+	opts.AddressRewriter = func(src, dst net.Addr) (net.Addr, net.Addr) {
+		ip := net.ParseIP("2001:db8::1")
+		return &net.TCPAddr{IP: ip, Port: 1}, &net.TCPAddr{IP: ip, Port: 2}
+	}
+
+	_, err = header.ReadFromWithOptions(bytes.NewReader(encodedHeaders[0]), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, AddressFamilyINET6, header.AddressFamily())
+}
+
+func TestMustEncode(t *testing.T) {
+	for i, header := range headers {
+		assert.Equal(t, expectedEncodedHeaders[i], MustEncode(header))
+	}
+
+	header := &Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[0].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeNOOP, make([]byte, 70000)}},
+	}
+
+	assert.Panics(t, func() { MustEncode(header) })
+}
+
+func TestHeader_RequiredMaxLength(t *testing.T) {
+	header := &Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[1].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeALPN, []byte("h2")}, {TLVTypeAUTHORITY, []byte("example.com")}},
+	}
+
+	length, err := header.RequiredMaxLength()
+	assert.Nil(t, err)
+	assert.Equal(t, AddressLength(55), length)
+
+	// It must match the length field actually written
+	encoded := MustEncode(header)
+	assert.Equal(t, []byte{0x00, 0x37}, encoded[14:16])
+
+	length, err = headers[0].RequiredMaxLength()
+	assert.Nil(t, err)
+	assert.Equal(t, AddressLength(12), length)
+
+	length, err = Header{Command: CommandLOCAL}.RequiredMaxLength()
+	assert.Nil(t, err)
+	assert.Equal(t, AddressLength(0), length)
+
+	// Headers that cannot be encoded are an error, like in WriteTo
+	oversized := &Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[0].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeNOOP, make([]byte, 40000)}, {TLVTypeNOOP, make([]byte, 40000)}},
+	}
+
+	invalid := []*Header{
+		oversized,
+		{Command: CommandPROXY},
+		{Command: CommandPROXY, ProxyAddress: headers[0].ProxyAddress, TLVs: []TLV{{TLVTypeNOOP, make([]byte, 70000)}}},
+	}
+
+	for _, header := range invalid {
+		length, err := header.RequiredMaxLength()
+		assert.NotNil(t, err)
+		assert.Equal(t, AddressLength(0), length)
+
+		_, err = header.WriteTo(&bytes.Buffer{})
+		assert.NotNil(t, err)
+	}
+
+	_, err = Header{Command: CommandPROXY}.RequiredMaxLength()
+	assert.Equal(t, ErrMissingAddress, err)
+}
+
+func TestHeader_EncodedLen(t *testing.T) {
+	for _, test := range benchmarkHeaders {
+		buffer := &bytes.Buffer{}
+		n, err := test.header.WriteTo(buffer)
+		assert.Nil(t, err, test.name)
+
+		length, err := test.header.EncodedLen()
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, int(n), length, test.name)
+		assert.Equal(t, buffer.Len(), length, test.name)
+	}
+
+	for i, header := range headers {
+		length, err := header.EncodedLen()
+		assert.Nil(t, err)
+		assert.Equal(t, len(expectedEncodedHeaders[i]), length)
+	}
+
+	udp := &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &IPv6Address{
+			SourceAddr:      &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 53},
+			DestinationAddr: &net.UDPAddr{IP: net.ParseIP("2001:db8::2"), Port: 53},
+		},
+	}
+
+	length, err := udp.EncodedLen()
+	assert.Nil(t, err)
+	assert.Equal(t, 52, length)
+	assert.Equal(t, len(MustEncode(udp)), length)
+
+	// Addresses and TLVs of LOCAL headers are not written
+	local := &Header{Command: CommandLOCAL, ProxyAddress: headers[0].ProxyAddress, TLVs: []TLV{{TLVTypeNOOP, nil}}}
+	length, err = local.EncodedLen()
+	assert.Nil(t, err)
+	assert.Equal(t, 16, length)
+	assert.Equal(t, len(MustEncode(local)), length)
+
+	// Headers that cannot be encoded have no length
+	oversized := &Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[0].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeNOOP, make([]byte, 40000)}, {TLVTypeNOOP, make([]byte, 40000)}},
+	}
+
+	for _, header := range []*Header{oversized, {Command: CommandPROXY}} {
+		length, err = header.EncodedLen()
+		assert.NotNil(t, err)
+		assert.Equal(t, 0, length)
+	}
+}
+
+func TestHeader_String(t *testing.T) {
+	headers := map[string]Header{
+		"PROXY TCP4 127.0.0.1:42446 -> 127.0.0.1:1338": {
+			Command: CommandPROXY,
+			ProxyAddress: &IPv4Address{
+				SourceAddr:      &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 42446},
+				DestinationAddr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1338},
+			},
+		},
+		"PROXY UDP6 [2607:f0d0:1002:51::4]:42446 -> [::1]:1338": {
+			Command: CommandPROXY,
+			ProxyAddress: &IPv6Address{
+				SourceAddr:      &net.UDPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 42446},
+				DestinationAddr: &net.UDPAddr{IP: net.ParseIP("::1"), Port: 1338},
+			},
+		},
+		"PROXY UNIX_STREAM /tmp/source.sock -> /tmp/destination.sock": {
+			Command: CommandPROXY,
+			ProxyAddress: &UnixAddr{
+				SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+				DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unix"},
+			},
+		},
+		"PROXY UNKNOWN": {Command: CommandPROXY},
+		"LOCAL":         {Command: CommandLOCAL},
+	}
+
+	for expected, header := range headers {
+		assert.Equal(t, expected, header.String())
+	}
+}
+
+func TestCommand_String(t *testing.T) {
+	assert.Equal(t, "LOCAL", CommandLOCAL.String())
+	assert.Equal(t, "PROXY", CommandPROXY.String())
+	assert.Equal(t, "UNKNOWN(0x05)", Command(0x05).String())
+}
+
+func TestTransportProtocolError_Error(t *testing.T) {
+	err := TransportProtocolError{TransportProtocolDGRAM, AddressFamilyUNSPEC, 12}
+	assert.Equal(t, "unsupported protocol DGRAM with address type UNSPEC (length 12)", err.Error())
+}
+
+func TestHeader_ReadFrom_MaxHeaderLength(t *testing.T) {
+	// TCP over IPv4 declaring 0xFFFF bytes of addresses and TLVs
+	data := append(Signature(), 0x21, 0x11, 0xFF, 0xFF)
+	reader := bytes.NewReader(append(data, make([]byte, 0xFFFF)...))
+
+	var header Header
+	n, err := header.ReadFrom(reader)
+	assert.Equal(t, &HeaderTooLongError{Length: 0xFFFF, MaxLength: DefaultMaxHeaderLength}, err)
+	assert.Equal(t, 16, int(n))
+
+	// The limit is checked against the declared length, so it can be set exactly
+	header = Header{}
+	_, err = header.ReadFromWithOptions(bytes.NewReader(expectedEncodedHeaders[0]), ReadOptions{MaxHeaderLength: 12})
+	assert.Nil(t, err)
+
+	_, err = header.ReadFromWithOptions(bytes.NewReader(expectedEncodedHeaders[0]), ReadOptions{MaxHeaderLength: 11})
+	assert.IsType(t, &HeaderTooLongError{}, err)
+}
+
+func TestHeader_MarshalBinary(t *testing.T) {
+	for i, header := range headers {
+		data, err := header.MarshalBinary()
+		assert.Nil(t, err)
+		assert.Equal(t, expectedEncodedHeaders[i], data)
+
+		var decoded Header
+		assert.Nil(t, decoded.UnmarshalBinary(data))
+		assert.Nil(t, header.SelfConsistent())
+		assert.Equal(t, header.Command, decoded.Command)
+		assert.True(t, equalProxyAddress(header.ProxyAddress, decoded.ProxyAddress))
+	}
+}
+
+func TestHeader_UnmarshalBinary_Invalid(t *testing.T) {
+	original := *headers[0]
+	header := original
+
+	// Trailing application data
+	err := header.UnmarshalBinary(append(append([]byte{}, expectedEncodedHeaders[1]...), 0x00))
+	assert.NotNil(t, err)
+	assert.Equal(t, original, header)
+
+	// Truncated header
+	err = header.UnmarshalBinary(expectedEncodedHeaders[1][:20])
+	assert.NotNil(t, err)
+	assert.Equal(t, original, header)
+}
+
+var benchmarkHeaders = []struct {
+	name   string
+	header *Header
+}{
+	{"IPv4TCP", &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+		},
+	}},
+	{"IPv6TCP", &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &IPv6Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("2345:425:2ca1::567:5673:23b5"), Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443},
+		},
+	}},
+	{"UNIX", &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+			DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unix"},
+		},
+	}},
+	{"IPv4TCPWithTLVs", &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+		},
+		TLVs: []TLV{
+			{TLVTypeALPN, []byte("h2")},
+			{TLVTypeAUTHORITY, []byte("example.com")},
+			{TLVTypeUNIQUEID, []byte("request-42")},
+		},
+	}},
+}
+
+func BenchmarkHeader_ReadFrom(b *testing.B) {
+	benchmarkReadFrom(b, ReadOptions{})
+}
+
+// benchmarkReadFrom reads every benchmark header with opts, reporting the
+// number of Read calls, each of which would be a system call if reading right
+// from a connection.
+func benchmarkReadFrom(b *testing.B, opts ReadOptions) {
+	for _, test := range benchmarkHeaders {
+		data := MustEncode(test.header)
+		b.Run(test.name, func(b *testing.B) {
+			b.ReportAllocs()
+			reader := &countingReader{Reader: bytes.NewReader(nil)}
+			for i := 0; i < b.N; i++ {
+				reader.Reader.(*bytes.Reader).Reset(data)
+
+				var header Header
+				_, err := header.ReadFromWithOptions(reader, opts)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ReportMetric(float64(reader.reads)/float64(b.N), "reads/op")
+		})
+	}
+}
+
+func BenchmarkHeader_WriteTo(b *testing.B) {
+	for _, test := range benchmarkHeaders {
+		header := test.header
+		b.Run(test.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := header.WriteTo(io.Discard)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// countingReader counts calls to Read, each of which would be a system call
+// if reading right from a connection.
+type countingReader struct {
+	io.Reader
+	reads int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads++
+	return c.Reader.Read(p)
+}
+
+func TestHeader_ReadFromWithOptions_Streaming(t *testing.T) {
+	header := Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[1].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeALPN, []byte("h2")}, {TLVTypeAUTHORITY, []byte("example.com")}},
+	}
+	data := MustEncode(&header)
+
+	var buffered, streamed Header
+	reader := &countingReader{Reader: bytes.NewReader(data)}
+	n, err := buffered.ReadFrom(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, len(data), int(n))
+
+	// Fixed 16-byte part, followed by the whole body
+	assert.Equal(t, 2, reader.reads)
+
+	reader = &countingReader{Reader: bytes.NewReader(data)}
+	n, err = streamed.ReadFromWithOptions(reader, ReadOptions{Streaming: true})
+	assert.Nil(t, err)
+	assert.Equal(t, len(data), int(n))
+	assert.Greater(t, reader.reads, 2)
+
+	assert.Equal(t, buffered, streamed)
+	assert.Nil(t, buffered.SelfConsistent())
+}
+
+// BenchmarkHeader_ReadFromWithOptions_Streaming reads the same headers as
+// BenchmarkHeader_ReadFrom, so that both paths can be compared.
+func BenchmarkHeader_ReadFromWithOptions_Streaming(b *testing.B) {
+	benchmarkReadFrom(b, ReadOptions{Streaming: true})
+}
+
+func TestHeader_AppendTo(t *testing.T) {
+	tlvHeader := &Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[1].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeALPN, []byte("h2")}},
+	}
+
+	dst := []byte("prefix")
+	for _, header := range append(headers, tlvHeader, &Header{Command: CommandLOCAL}) {
+		buffer := &bytes.Buffer{}
+		_, err := header.WriteTo(buffer)
+		assert.Nil(t, err)
+
+		data, err := header.AppendTo(dst[:6])
+		assert.Nil(t, err)
+		assert.Equal(t, "prefix", string(data[:6]))
+		assert.Equal(t, buffer.Bytes(), data[6:])
+		dst = data
+	}
+
+	// Nothing is appended if the header cannot be encoded
+	invalid := Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[0].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeALPN, make([]byte, 70000)}},
+	}
+
+	data, err := invalid.AppendTo(dst[:6])
+	assert.NotNil(t, err)
+	assert.Equal(t, "prefix", string(data))
+}
+
+func TestHeader_AppendTo_Allocations(t *testing.T) {
+	dst := make([]byte, 0, 256)
+	allocations := testing.AllocsPerRun(100, func() {
+		_, _ = headers[0].AppendTo(dst[:0])
+	})
+
+	assert.Equal(t, 0.0, allocations)
+}
+
+func TestHeader_ReadFrom_UnsupportedVersion(t *testing.T) {
+	data := append([]byte{}, expectedEncodedHeaders[0]...)
+	data[12] = 0x31 // Version 3, PROXY
+
+	var header Header
+	n, err := header.ReadFrom(bytes.NewReader(data))
+	assert.Equal(t, &UnsupportedVersionError{Version: 3}, err)
+	assert.Equal(t, "unsupported protocol version: expected 2, but got 3", err.Error())
+	// Whole fixed part is read at once
+	assert.Equal(t, 16, int(n))
+
+	// Version is rejected even if the rest of the fixed part never arrives
+	n, err = header.ReadFrom(bytes.NewReader(data[:13]))
+	assert.Equal(t, &UnsupportedVersionError{Version: 3}, err)
+	assert.Equal(t, 13, int(n))
+
+	data[12] = 0x25 // Version 2, unassigned command
+	_, err = header.ReadFrom(bytes.NewReader(data))
+	assert.Equal(t, &UnsupportedCommandError{Command: 5}, err)
+	assert.Equal(t, "unsupported command: expected either 0x0 or 0x1, but got UNKNOWN(0x05)", err.Error())
+}
+
+func TestReadHeaderFrom(t *testing.T) {
+	for _, data := range [][]byte{expectedEncodedHeaders[1], []byte("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n")} {
+		payload := []byte("GET / HTTP/1.1\r\n\r\n")
+
+		header, rest, err := ReadHeaderFrom(bytes.NewReader(append(append([]byte{}, data...), payload...)))
+		assert.Nil(t, err)
+		assert.Equal(t, CommandPROXY, header.Command)
+
+		// Bytes buffered while parsing the header must be returned
+		remaining, err := io.ReadAll(rest)
+		assert.Nil(t, err)
+		assert.Equal(t, payload, remaining)
+	}
+
+	_, _, err := ReadHeaderFrom(strings.NewReader("GET / HTTP/1.1\r\n\r\n"))
+	assert.IsType(t, &ProxyProtocolError{}, err)
+}
+
+func TestParseHeader(t *testing.T) {
+	v1 := []byte("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n")
+	for _, data := range [][]byte{expectedEncodedHeaders[1], v1} {
+		header, n, err := ParseHeader(append(append([]byte{}, data...), "payload"...))
+		assert.Nil(t, err)
+		assert.Equal(t, len(data), n)
+		assert.Equal(t, CommandPROXY, header.Command)
+
+		// Every prefix of the header is incomplete, not malformed
+		for i := 0; i < len(data); i++ {
+			_, _, err = ParseHeader(data[:i])
+			assert.True(t, errors.Is(err, ErrIncompleteHeader), i)
+		}
+	}
+
+	_, _, err := ParseHeader([]byte("GET"))
+	assert.IsType(t, &ProxyProtocolError{}, err)
+
+	_, _, err = ParseHeader([]byte("PROXY TCP4 192.168.0.1 192.168.0.11 56324 70000\r\n"))
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, ErrIncompleteHeader))
+}
+
+func TestHeader_ReadFrom_Incomplete(t *testing.T) {
+	data := expectedEncodedHeaders[1]
+	tests := map[int]int{
+		0:  12,        // Nothing
+		5:  12,        // Part of signature
+		12: 13,        // Signature without version
+		15: 16,        // Part of length
+		20: len(data), // Part of addresses
+	}
+
+	for read, expected := range tests {
+		for _, opts := range []ReadOptions{{}, {Streaming: true}} {
+			var header Header
+			_, err := header.ReadFromWithOptions(bytes.NewReader(data[:read]), opts)
+			assert.Equal(t, &IncompleteHeaderError{Read: read, Expected: expected}, err, read)
+			assert.True(t, errors.Is(err, ErrIncompleteHeader))
+		}
+	}
+
+	var header Header
+	_, err := header.ReadFromV1(strings.NewReader("PROXY TCP4 192.168.0.1"))
+	assert.Equal(t, &IncompleteHeaderError{Read: 22, Expected: 23}, err)
+}
+
+func TestHeader_ReadFrom_LengthMismatch(t *testing.T) {
+	tests := []struct {
+		protocol byte
+		length   AddressLength
+		expected AddressLength
+	}{
+		{0x11, 4, 12},    // TCP over IPv4 without ports
+		{0x21, 12, 36},   // TCP over IPv6 with length of IPv4
+		{0x22, 35, 36},   // UDP over IPv6 one byte short
+		{0x31, 108, 216}, // UNIX stream with source path only
+	}
+
+	for _, test := range tests {
+		data := append(Signature(), 0x21, test.protocol, byte(test.length>>8), byte(test.length))
+		data = append(data, make([]byte, test.length)...)
+
+		var header Header
+		n, err := header.ReadFrom(bytes.NewReader(data))
+		assert.Equal(t, 16, int(n))
+		assert.Equal(t, &LengthMismatchError{
+			AddressFamily:     AddressFamily(test.protocol >> 4),
+			TransportProtocol: TransportProtocol(test.protocol & 0xF),
+			Length:            test.length,
+			ExpectedLength:    test.expected,
+		}, err)
+	}
+}
+
+func TestHeader_ReadFrom_LocalWithAddress(t *testing.T) {
+	data := append([]byte{}, expectedEncodedHeaders[0]...)
+	data[12] = 0x20 // LOCAL
+	data = append(data, "payload"...)
+
+	for _, opts := range []ReadOptions{{}, {Streaming: true}} {
+		reader := bytes.NewReader(data)
+
+		var header Header
+		n, err := header.ReadFromWithOptions(reader, opts)
+		assert.Nil(t, err)
+		assert.Equal(t, len(expectedEncodedHeaders[0]), int(n))
+		assert.Equal(t, CommandLOCAL, header.Command)
+		assert.Nil(t, header.ProxyAddress)
+
+		// Address block must be skipped
+		assert.Equal(t, len("payload"), reader.Len())
+	}
+}
+
+func FuzzReadFrom(f *testing.F) {
+	for _, data := range append(append(encodedHeaders, expectedEncodedHeaders...), checksumHeader) {
+		f.Add(data)
+	}
+
+	f.Add([]byte("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n"))
+	f.Add([]byte("PROXY UNKNOWN\r\n"))
+
+	// Truncated and oversized length fields
+	f.Add(append(Signature(), 0x21, 0x11, 0x00, 0x0B))
+	f.Add(append(Signature(), 0x21, 0x31, 0xFF, 0xFF))
+	f.Add(append(Signature(), 0x21, 0x00, 0x00, 0x10))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		header, n, err := ParseHeader(data)
+		if err != nil {
+			return
+		}
+
+		if header == nil || n > len(data) {
+			t.Fatalf("ParseHeader returned header %v after %d of %d bytes without error", header, n, len(data))
+		}
+
+		_, _ = header.WriteToV1(io.Discard)
+		_, _ = header.AppendTo(nil)
+		_ = header.String()
+
+		for _, opts := range []ReadOptions{{VerifyChecksum: true}, {Streaming: true}} {
+			var decoded Header
+			_, _ = decoded.ReadFromWithOptions(bytes.NewReader(data), opts)
+		}
+	})
+}
+
+func TestHeader_RoundTrip_UDP(t *testing.T) {
+	addresses := [][2]*net.UDPAddr{
+		{{IP: net.ParseIP("192.168.0.1"), Port: 56324}, {IP: net.ParseIP("192.168.0.11"), Port: 443}},
+		{{IP: net.IPv4(10, 0, 0, 1).To4(), Port: 1}, {IP: net.IPv4(10, 0, 0, 2).To4(), Port: 65535}},
+		{{IP: net.ParseIP("2345:425:2ca1::567:5673:23b5"), Port: 56324}, {IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443}},
+	}
+
+	for _, pair := range addresses {
+		address, err := WrapAddress(pair[0], pair[1])
+		assert.Nil(t, err)
+
+		header := Header{Command: CommandPROXY, ProxyAddress: address}
+		buffer := &bytes.Buffer{}
+		_, err = header.WriteTo(buffer)
+		assert.Nil(t, err)
+
+		// Transport protocol is sent as DGRAM
+		assert.Equal(t, byte(TransportProtocolDGRAM), buffer.Bytes()[13]&0x0F)
+
+		var decoded Header
+		_, err = decoded.ReadFrom(buffer)
+		assert.Nil(t, err)
+		assert.IsType(t, address, decoded.ProxyAddress)
+
+		source, destination := decoded.ProxyAddress.getAddresses()
+		assert.IsType(t, &net.UDPAddr{}, source)
+		assert.IsType(t, &net.UDPAddr{}, destination)
+		assert.Equal(t, "udp", source.Network())
+		assert.True(t, pair[0].IP.Equal(source.(*net.UDPAddr).IP))
+		assert.True(t, pair[1].IP.Equal(destination.(*net.UDPAddr).IP))
+		assert.Equal(t, pair[0].Port, source.(*net.UDPAddr).Port)
+		assert.Equal(t, pair[1].Port, destination.(*net.UDPAddr).Port)
+
+		// Decoded header is written back byte for byte
+		assert.Equal(t, MustEncode(&header), MustEncode(&decoded))
+	}
+}
+
+func TestSignature(t *testing.T) {
+	signature := Signature()
+	signature[0] = 'X'
+	assert.Equal(t, byte(0x0D), Signature()[0])
+
+	// Modifying the deprecated variable does not affect parsing
+	ProtocolSignature[0] = 'X'
+	defer func() { ProtocolSignature[0] = 0x0D }()
+
+	var header Header
+	_, err := header.ReadFrom(bytes.NewReader(encodedHeaders[0]))
+	assert.Nil(t, err)
+	assert.Equal(t, encodedHeaders[0], MustEncode(&header))
+}
+
+// failingWriter accepts limit bytes and fails to write anything after that.
+type failingWriter struct {
+	limit   int
+	written []byte
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if len(f.written)+len(p) <= f.limit {
+		f.written = append(f.written, p...)
+		return len(p), nil
+	}
+
+	n := f.limit - len(f.written)
+	f.written = append(f.written, p[:n]...)
+	return n, errWriteFailed
+}
+
+func TestHeader_WriteTo_FailingWriter(t *testing.T) {
+	tlvHeader := &Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[0].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeALPN, []byte("h2")}},
+	}
+
+	for _, header := range append(headers, tlvHeader, NewLocalHeader()) {
+		data := MustEncode(header)
+		for limit := 0; limit < len(data); limit++ {
+			w := &failingWriter{limit: limit, written: []byte{}}
+			n, err := header.WriteTo(w)
+			assert.Equal(t, errWriteFailed, err)
+			assert.Equal(t, int64(limit), n)
+			assert.Equal(t, data[:limit], w.written)
+
+			w = &failingWriter{limit: limit}
+			n, err = header.WriteToWithOptions(w, WriteOptions{Checksum: true})
+			assert.Equal(t, errWriteFailed, err)
+			assert.Equal(t, int64(limit), n)
+		}
+	}
+}
+
+func TestHeader_WriteTo_FailingWriter_Parts(t *testing.T) {
+	writers := []io.WriterTo{
+		AddressLength(0x1234),
+		VersionByte{ProtocolVersion: 2, Command: CommandPROXY},
+		ProtocolByte{AddressFamilyINET, TransportProtocolSTREAM},
+		TLV{TLVTypeALPN, []byte("h2")},
+		headers[0].ProxyAddress,
+	}
+
+	for _, writer := range writers {
+		buffer := &bytes.Buffer{}
+		_, err := writer.WriteTo(buffer)
+		assert.Nil(t, err)
+
+		for limit := 0; limit < buffer.Len(); limit++ {
+			n, err := writer.WriteTo(&failingWriter{limit: limit})
+			assert.Equal(t, errWriteFailed, err)
+			assert.Equal(t, int64(limit), n, "%T", writer)
+		}
+	}
+}
+
+func TestHeader_ReadFrom_Truncated(t *testing.T) {
+	for _, data := range expectedEncodedHeaders {
+		for length := 0; length < len(data); length++ {
+			for _, opts := range []ReadOptions{{}, {Streaming: true}} {
+				var header Header
+				n, err := header.ReadFromWithOptions(bytes.NewReader(data[:length]), opts)
+				assert.NotNil(t, err)
+				assert.Equal(t, int64(length), n)
+			}
+		}
+	}
+}
+
+// recordingWriter keeps data of every Write call separately.
+type recordingWriter struct {
+	writes [][]byte
+}
+
+func (r *recordingWriter) Write(p []byte) (int, error) {
+	r.writes = append(r.writes, append([]byte{}, p...))
+	return len(p), nil
+}
+
+func TestHeader_WriteTo_SingleWrite(t *testing.T) {
+	tlvHeader := &Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[0].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeALPN, []byte("h2")}, {TLVTypeAUTHORITY, []byte("example.com")}},
+	}
+
+	// The whole frame is passed to the writer at once, so that it is never
+	// split between system calls on a connection
+	for _, header := range append(headers, tlvHeader, NewLocalHeader()) {
+		w := &recordingWriter{}
+		_, err := header.WriteTo(w)
+		assert.Nil(t, err)
+		assert.Equal(t, [][]byte{MustEncode(header)}, w.writes)
+
+		w = &recordingWriter{}
+		_, err = header.WriteToWithOptions(w, WriteOptions{Checksum: true})
+		assert.Nil(t, err)
+		assert.Len(t, w.writes, 1)
+	}
+
+	w := &recordingWriter{}
+	_, err := headers[0].WriteToV1(w)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{[]byte("PROXY TCP4 127.0.0.1 127.0.0.1 42446 1338\r\n")}, w.writes)
+}
+
+// shortWriter accepts at most limit bytes per call without reporting an
+// error, like some non-blocking writers do.
+type shortWriter struct {
+	limit int
+	bytes.Buffer
+}
+
+func (s *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > s.limit {
+		p = p[:s.limit]
+	}
+
+	return s.Buffer.Write(p)
+}
+
+func TestHeader_WriteTo_ShortWrites(t *testing.T) {
+	for _, header := range append(headers, NewLocalHeader()) {
+		w := &shortWriter{limit: 3}
+		n, err := header.WriteTo(w)
+		assert.Nil(t, err)
+		assert.Equal(t, MustEncode(header), w.Bytes())
+		assert.Equal(t, int64(w.Len()), n)
+	}
+
+	w := &shortWriter{limit: 3}
+	n, err := headers[0].WriteToV1(w)
+	assert.Nil(t, err)
+	assert.Equal(t, "PROXY TCP4 127.0.0.1 127.0.0.1 42446 1338\r\n", w.String())
+	assert.Equal(t, int64(w.Len()), n)
+
+	// Writer that makes no progress must not be retried forever
+	w = &shortWriter{limit: 0}
+	n, err = headers[0].WriteTo(w)
+	assert.Equal(t, io.ErrShortWrite, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestHeader_AddressFamily(t *testing.T) {
+	tests := []struct {
+		header    *Header
+		family    AddressFamily
+		transport TransportProtocol
+	}{
+		{headers[0], AddressFamilyINET, TransportProtocolSTREAM},
+		{headers[1], AddressFamilyINET6, TransportProtocolDGRAM},
+		{&Header{Command: CommandPROXY, ProxyAddress: &UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unixgram"},
+			DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unixgram"},
+		}}, AddressFamilyUNIX, TransportProtocolDGRAM},
+		{NewLocalHeader(), AddressFamilyUNSPEC, TransportProtocolUNSPEC},
+		{&Header{Command: CommandLOCAL, ProxyAddress: headers[0].ProxyAddress}, AddressFamilyUNSPEC, TransportProtocolUNSPEC},
+		{&Header{Command: CommandPROXY}, AddressFamilyUNSPEC, TransportProtocolUNSPEC},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.family, test.header.AddressFamily(), test.header.String())
+		assert.Equal(t, test.transport, test.header.TransportProtocol(), test.header.String())
+	}
+}
+
+func TestHeader_ReadFromWithOptions_Streaming_NoOverRead(t *testing.T) {
+	tlvHeader := &Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[1].ProxyAddress,
+		TLVs:         []TLV{{TLVTypeALPN, []byte("h2")}, {TLVTypeAUTHORITY, []byte("example.com")}},
+	}
+
+	payload := []byte("GET / HTTP/1.1\r\n\r\n")
+	for _, header := range []*Header{headers[0], headers[1], tlvHeader} {
+		data := MustEncode(header)
+		reader := bytes.NewReader(append(append([]byte{}, data...), payload...))
+
+		var decoded Header
+		n, err := decoded.ReadFromWithOptions(iotest.OneByteReader(reader), ReadOptions{Streaming: true})
+		assert.Nil(t, err)
+		assert.Equal(t, len(data), int(n))
+
+		// Application data is left untouched
+		remaining, err := io.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, payload, remaining)
+	}
+}
+
+func TestHeader_ReadFrom_UnixOneByte(t *testing.T) {
+	header := &Header{
+		Command: CommandPROXY,
+		ProxyAddress: &UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+			DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unix"},
+		},
+		TLVs: []TLV{{TLVTypeALPN, []byte("h2")}, {TLVTypeAUTHORITY, []byte("example.com")}},
+	}
+
+	data := MustEncode(header)
+	payload := []byte("GET / HTTP/1.1\r\n\r\n")
+
+	for _, opts := range []ReadOptions{{}, {Streaming: true}} {
+		reader := bytes.NewReader(append(append([]byte{}, data...), payload...))
+
+		var decoded Header
+		n, err := decoded.ReadFromWithOptions(iotest.OneByteReader(reader), opts)
+		assert.Nil(t, err)
+		assert.Equal(t, len(data), int(n))
+		assert.Equal(t, header.ProxyAddress, decoded.ProxyAddress)
+		assert.Equal(t, header.TLVs, decoded.TLVs)
+
+		remaining, err := io.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, payload, remaining)
+	}
+}
+
+func TestHeader_RoundTrip(t *testing.T) {
+	ipv4Source, ipv4Destination := net.IPv4(192, 168, 0, 1).To4(), net.IPv4(192, 168, 0, 11).To4()
+	ipv6Source, ipv6Destination := net.ParseIP("2345:425:2ca1::567:5673:23b5"), net.ParseIP("2607:f0d0:1002:51::4")
+
+	addresses := map[string]ProxyAddress{
+		"IPv4 TCP": &IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: ipv4Source, Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: ipv4Destination, Port: 443},
+		},
+		"IPv4 UDP": &IPv4Address{
+			SourceAddr:      &net.UDPAddr{IP: ipv4Source, Port: 56324},
+			DestinationAddr: &net.UDPAddr{IP: ipv4Destination, Port: 443},
+		},
+		"IPv6 TCP": &IPv6Address{
+			SourceAddr:      &net.TCPAddr{IP: ipv6Source, Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: ipv6Destination, Port: 443},
+		},
+		"IPv6 UDP": &IPv6Address{
+			SourceAddr:      &net.UDPAddr{IP: ipv6Source, Port: 56324},
+			DestinationAddr: &net.UDPAddr{IP: ipv6Destination, Port: 443},
+		},
+		"UNIX stream": &UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/var/run/source.sock", Net: "unix"},
+			DestinationAddr: &net.UnixAddr{Name: "/var/run/destination.sock", Net: "unix"},
+		},
+		"UNIX datagram": &UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/var/run/source.sock", Net: "unixgram"},
+			DestinationAddr: &net.UnixAddr{Name: "/var/run/destination.sock", Net: "unixgram"},
+		},
+	}
+
+	for name, address := range addresses {
+		header := &Header{Command: CommandPROXY, ProxyAddress: address}
+		data := MustEncode(header)
+		assert.Equal(t, 16+int(address.getLength()), len(data), name)
+
+		for _, opts := range []ReadOptions{{}, {Streaming: true}} {
+			var decoded Header
+			n, err := decoded.ReadFromWithOptions(bytes.NewReader(data), opts)
+			assert.Nil(t, err, name)
+			assert.Equal(t, len(data), int(n), name)
+			assert.Equal(t, header, &decoded, name)
+		}
+	}
+}
+
+func TestHeader_WriteTo_MissingAddress(t *testing.T) {
+	header := Header{Command: CommandPROXY, TLVs: []TLV{{TLVTypeALPN, []byte("h2")}}}
+
+	for _, opts := range []WriteOptions{{}, {Checksum: true}} {
+		buffer := &bytes.Buffer{}
+		n, err := header.WriteToWithOptions(buffer, opts)
+		assert.Equal(t, ErrMissingAddress, err)
+		assert.Equal(t, int64(0), n)
+		assert.Equal(t, 0, buffer.Len())
+	}
+
+	_, err := header.AppendTo(nil)
+	assert.Equal(t, ErrMissingAddress, err)
+
+	_, err = header.Buffers()
+	assert.Equal(t, ErrMissingAddress, err)
+
+	// LOCAL headers have no address
+	_, err = Header{Command: CommandLOCAL}.WriteTo(&bytes.Buffer{})
+	assert.Nil(t, err)
+}
+
+func TestHeader_ReadFromWithOptions_StrictMode(t *testing.T) {
+	tests := []struct {
+		data   []byte
+		strict error
+	}{
+		{append(Signature(), 0x21, 0x00, 0x00, 0x00), ErrUnspecifiedProtocol}, // PROXY, UNSPEC
+		{append(Signature(), 0x21, 0x10, 0x00, 0x00), ErrUnspecifiedProtocol}, // PROXY, IPv4 with UNSPEC transport
+		{append(Signature(), 0x21, 0x01, 0x00, 0x00), ErrUnspecifiedProtocol}, // PROXY, TCP with UNSPEC family
+		{append(Signature(), 0x20, 0x00, 0x00, 0x00), nil},                    // LOCAL, UNSPEC
+		{expectedEncodedHeaders[0], nil},
+	}
+
+	for _, test := range tests {
+		var header Header
+		_, err := header.ReadFromWithOptions(bytes.NewReader(test.data), ReadOptions{StrictMode: true})
+		assert.Equal(t, test.strict, err, test.data)
+
+		// Only strict mode rejects them
+		if test.data[13] == 0x00 {
+			_, err = header.ReadFrom(bytes.NewReader(test.data))
+			assert.Nil(t, err)
+		}
+	}
+}
+
+func TestIsProxyHeader(t *testing.T) {
+	tests := []struct {
+		peek []byte
+		isV1 bool
+		isV2 bool
+	}{
+		{expectedEncodedHeaders[0][:SignatureLength], false, true},
+		{expectedEncodedHeaders[0], false, true},
+		{[]byte("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n"), true, false},
+		{[]byte("PROXY "), true, false},
+		{[]byte("GET / HTTP/1.1\r\n"), false, false},
+		{[]byte{0x16, 0x03, 0x01, 0x02, 0x00, 0x01, 0x00, 0x01, 0xfc, 0x03, 0x03, 0x00}, false, false}, // TLS ClientHello
+		{expectedEncodedHeaders[0][:SignatureLength-1], false, false},                                  // Too short to tell
+		{[]byte("PROXY"), false, false},
+		{nil, false, false},
+	}
+
+	for _, test := range tests {
+		isV1, isV2 := IsProxyHeader(test.peek)
+		assert.Equal(t, test.isV1, isV1, test.peek)
+		assert.Equal(t, test.isV2, isV2, test.peek)
+	}
+}
+
+func TestHeader_Reset(t *testing.T) {
+	header := Header{Command: CommandPROXY, ProxyAddress: headers[0].ProxyAddress, TLVs: []TLV{{TLVTypeALPN, []byte("h2")}}}
+	header.Reset()
+	assert.Equal(t, Header{}, header)
+}
+
+func TestHeader_ReadFrom_Reuse(t *testing.T) {
+	proxy := &Header{Command: CommandPROXY, ProxyAddress: headers[0].ProxyAddress, TLVs: []TLV{{TLVTypeALPN, []byte("h2")}}}
+
+	var header Header
+	_, err := header.ReadFrom(bytes.NewReader(MustEncode(proxy)))
+	assert.Nil(t, err)
+	assert.Equal(t, proxy, &header)
+
+	_, err = header.ReadFrom(bytes.NewReader(MustEncode(NewLocalHeader())))
+	assert.Nil(t, err)
+	assert.Equal(t, NewLocalHeader(), &header)
+}
+
+func TestHeader_ReadFrom_NoStaleFields(t *testing.T) {
+	zeroLength := [][]byte{
+		append(Signature(), 0x20, 0x00, 0x00, 0x00), // LOCAL, UNSPEC
+		append(Signature(), 0x20, 0x11, 0x00, 0x00), // LOCAL, TCP over IPv4 without addresses
+		append(Signature(), 0x21, 0x00, 0x00, 0x00), // PROXY, UNSPEC
+	}
+
+	for _, data := range zeroLength {
+		for _, opts := range []ReadOptions{{}, {Streaming: true}} {
+			header := Header{Command: CommandPROXY, ProxyAddress: headers[0].ProxyAddress, TLVs: []TLV{{TLVTypeALPN, []byte("h2")}}}
+			_, err := header.ReadFromWithOptions(bytes.NewReader(data), opts)
+			assert.Nil(t, err)
+			assert.Nil(t, header.ProxyAddress, data)
+			assert.Nil(t, header.TLVs, data)
+			assert.Equal(t, Command(data[12]&0x0F), header.Command)
+		}
+	}
+}
+
+func TestHeader_ReadFromWithOptions_AllowUnsupportedProtocol(t *testing.T) {
+	data := encodedHeaders[len(encodedHeaders)-1]
+
+	for _, streaming := range []bool{false, true} {
+		reader := bytes.NewReader(data)
+		opts := ReadOptions{AllowUnsupportedProtocol: true, Streaming: streaming}
+
+		header := Header{ProxyAddress: headers[0].ProxyAddress}
+		n, err := header.ReadFromWithOptions(reader, opts)
+		assert.Nil(t, err)
+		assert.Equal(t, 48, int(n))
+		assert.Equal(t, CommandPROXY, header.Command)
+		assert.Nil(t, header.ProxyAddress)
+		assert.Nil(t, header.TLVs)
+		assert.True(t, header.UnknownAddresses())
+		assert.True(t, header.Unsupported)
+
+		// Data that follows the header is left for the application
+		assert.Equal(t, 8, reader.Len())
+	}
+
+	// Flag is set only for unsupported protocols, and is not kept from the
+	// previous read
+	header := Header{Unsupported: true}
+	opts := ReadOptions{AllowUnsupportedProtocol: true}
+	streams := [][]byte{
+		expectedEncodedHeaders[0],
+		append(Signature(), 0x21, 0x00, 0x00, 0x00), // PROXY, UNSPEC without addresses
+		append(Signature(), 0x20, 0x00, 0x00, 0x00), // LOCAL, UNSPEC
+	}
+
+	for _, data := range streams {
+		_, err := header.ReadFromWithOptions(bytes.NewReader(data), opts)
+		assert.Nil(t, err)
+		assert.False(t, header.Unsupported, data)
+	}
+
+	header = Header{Unsupported: true}
+	_, err := header.ReadFromV1(strings.NewReader("PROXY UNKNOWN\r\n"))
+	assert.Nil(t, err)
+	assert.True(t, header.UnknownAddresses())
+	assert.False(t, header.Unsupported)
+}
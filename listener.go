@@ -0,0 +1,92 @@
+package haproxy
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// ErrUntrustedProxy is returned when reading from a connection that came from
+// a peer not listed in Listener.TrustedProxies, while a header is required.
+var ErrUntrustedProxy = errors.New("connection does not come from a trusted proxy")
+
+// Listener wraps a net.Listener and returns every accepted connection as
+// *Conn, which reports addresses from the PROXY header in RemoteAddr and
+// LocalAddr, and starts reading right after the header. Headers are read
+// lazily, so a slow client does not block Accept.
+type Listener struct {
+	net.Listener
+
+	// AllowMissingHeader defines what happens with connections that do not
+	// start with a header signature. By default, they are closed as soon as
+	// the header is read, and reading from them fails. If true, they are
+	// passed through as is, reporting the addresses of the socket, with all
+	// data that was read while looking for the header available for reading
+	// again. Connections that start with a signature, but carry a malformed
+	// header are closed either way.
+	//
+	// Allowing connections without header lets any client that can reach the
+	// listener directly connect under its own address, so it is only safe
+	// when direct clients are trusted as much as the proxy, e.g. health
+	// checks on a private network. Use TrustedProxies to make sure that
+	// headers are only accepted from the proxy.
+	AllowMissingHeader bool
+
+	// TrustedProxies, if not empty, limits the peers whose headers are
+	// honored, like reverse proxies only trust X-Forwarded-For from known
+	// hops. Connections from other peers are closed with ErrUntrustedProxy
+	// without reading anything, unless AllowMissingHeader is set. In that
+	// case, they report the addresses of the socket, and a header they send
+	// is read and discarded, so that it does not reach the application as
+	// data, while a malformed one still closes the connection. Peers without
+	// IP address, e.g. on UNIX sockets, are never trusted when the list is
+	// set.
+	TrustedProxies []net.IPNet
+
+	// MaxHeaderReadTime, if non-zero, limits the time a client is given to
+	// send the header, protecting against clients that hold connections
+	// open by sending the header slowly. The limit does not apply to data
+	// that follows the header. Connections that exceed it are closed, and
+	// reading from them returns the timeout error.
+	MaxHeaderReadTime time.Duration
+}
+
+// NewListener wraps l into a Listener that requires a header on every
+// connection, which is the same as &Listener{Listener: l}.
+func NewListener(l net.Listener) *Listener {
+	return &Listener{Listener: l}
+}
+
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conn{
+		Conn:              conn,
+		requireHeader:     !l.AllowMissingHeader,
+		headerReadTimeout: l.MaxHeaderReadTime,
+		untrusted:         !l.trusted(conn.RemoteAddr()),
+	}, nil
+}
+
+// trusted reports whether headers from the given peer can be honored.
+func (l *Listener) trusted(addr net.Addr) bool {
+	if len(l.TrustedProxies) == 0 {
+		return true
+	}
+
+	ip := getIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range l.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
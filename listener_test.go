@@ -0,0 +1,310 @@
+package haproxy
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testListener(t *testing.T) (net.Listener, func(data []byte) net.Conn) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	dial := func(data []byte) net.Conn {
+		conn, err := net.Dial("tcp", inner.Addr().String())
+		assert.Nil(t, err)
+
+		_, err = conn.Write(data)
+		assert.Nil(t, err)
+		return conn
+	}
+
+	return inner, dial
+}
+
+func TestListener_Accept(t *testing.T) {
+	inner, dial := testListener(t)
+	listener := NewListener(inner)
+	defer listener.Close()
+
+	client := dial(append(append([]byte{}, encodedHeaders[0]...), "hello"...))
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, &net.TCPAddr{IP: []byte{127, 0, 0, 1}, Port: 42446}, conn.RemoteAddr())
+	assert.Equal(t, &net.TCPAddr{IP: []byte{127, 0, 0, 1}, Port: 1338}, conn.LocalAddr())
+
+	data := make([]byte, 5)
+	_, err = io.ReadFull(conn, data)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestListener_Accept_RequireHeader(t *testing.T) {
+	inner, dial := testListener(t)
+	listener := NewListener(inner)
+	defer listener.Close()
+
+	client := dial([]byte("GET / HTTP/1.1\r\n"))
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	_, err = conn.Read(make([]byte, 1))
+	assert.IsType(t, &ProxyProtocolError{}, err)
+	assert.Equal(t, client.LocalAddr(), conn.RemoteAddr())
+
+	// Rejected connection must be closed by the listener
+	_, err = client.Read(make([]byte, 1))
+	assert.NotNil(t, err)
+}
+
+func TestListener_Accept_ZeroValue(t *testing.T) {
+	inner, dial := testListener(t)
+	listener := &Listener{Listener: inner}
+	defer listener.Close()
+
+	client := dial([]byte("GET / HTTP/1.1\r\n"))
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	_, err = conn.Read(make([]byte, 1))
+	assert.IsType(t, &ProxyProtocolError{}, err)
+}
+
+func TestListener_Accept_PassThrough(t *testing.T) {
+	inner, dial := testListener(t)
+	listener := &Listener{Listener: inner, AllowMissingHeader: true}
+	defer listener.Close()
+
+	client := dial([]byte("GET / HTTP/1.1\r\n"))
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+	assert.Equal(t, client.LocalAddr(), conn.RemoteAddr())
+
+	data := make([]byte, 16)
+	_, err = io.ReadFull(conn, data)
+	assert.Nil(t, err)
+	assert.Equal(t, "GET / HTTP/1.1\r\n", string(data))
+}
+
+func TestListener_Accept_PassThrough_Short(t *testing.T) {
+	inner, dial := testListener(t)
+	listener := &Listener{Listener: inner, AllowMissingHeader: true}
+	defer listener.Close()
+
+	// Client sends less than a signature and waits for response
+	client := dial([]byte("hi"))
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	data := make([]byte, 2)
+	_, err = io.ReadFull(conn, data)
+	assert.Nil(t, err)
+	assert.Equal(t, "hi", string(data))
+}
+
+func TestListener_Accept_PassThrough_Malformed(t *testing.T) {
+	inner, dial := testListener(t)
+	listener := &Listener{Listener: inner, AllowMissingHeader: true}
+	defer listener.Close()
+
+	// Valid signature followed by unsupported version
+	data := append([]byte{}, encodedHeaders[0]...)
+	data[12] = 0x31
+
+	client := dial(data)
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	_, err = conn.Read(make([]byte, 1))
+	assert.IsType(t, &UnsupportedVersionError{}, err)
+}
+
+func TestListener_Accept_MaxHeaderReadTime(t *testing.T) {
+	inner, dial := testListener(t)
+	listener := NewListener(inner)
+	listener.MaxHeaderReadTime = 50 * time.Millisecond
+	defer listener.Close()
+
+	// Send only part of the header and stall
+	slow := dial(encodedHeaders[0][:14])
+	defer slow.Close()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	_, err = conn.Read(make([]byte, 1))
+	assert.NotNil(t, err)
+	netErr, ok := err.(net.Error)
+	assert.True(t, ok)
+	assert.True(t, netErr.Timeout())
+
+	// Deadline must not affect reading data that follows the header
+	fast := dial(encodedHeaders[0])
+	defer fast.Close()
+
+	conn, err = listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = fast.Write([]byte("hello"))
+	}()
+
+	data := make([]byte, 5)
+	_, err = io.ReadFull(conn, data)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestListener_Accept_MaxHeaderReadTime_CallerDeadline(t *testing.T) {
+	inner, dial := testListener(t)
+	listener := NewListener(inner)
+	listener.MaxHeaderReadTime = 5 * time.Second
+	defer listener.Close()
+
+	// Deadline set before the first Read, like http.Server does, must survive
+	// reading the header
+	client := dial(encodedHeaders[0])
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	assert.Nil(t, conn.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+
+	start := time.Now()
+	_, err = conn.Read(make([]byte, 1))
+	netErr, ok := err.(net.Error)
+	assert.True(t, ok)
+	assert.True(t, netErr.Timeout())
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Caller deadline earlier than MaxHeaderReadTime also bounds the header
+	slow := dial(encodedHeaders[0][:14])
+	defer slow.Close()
+
+	conn, err = listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	assert.Nil(t, conn.SetDeadline(time.Now().Add(100*time.Millisecond)))
+
+	start = time.Now()
+	_, err = conn.Read(make([]byte, 1))
+	netErr, ok = err.(net.Error)
+	assert.True(t, ok)
+	assert.True(t, netErr.Timeout())
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestListener_Accept_TrustedProxies(t *testing.T) {
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	_, private, _ := net.ParseCIDR("10.0.0.0/8")
+
+	inner, dial := testListener(t)
+	listener := NewListener(inner)
+	listener.TrustedProxies = []net.IPNet{*private, *loopback}
+	defer listener.Close()
+
+	client := dial(append(append([]byte{}, encodedHeaders[0]...), "hello"...))
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, &net.TCPAddr{IP: []byte{127, 0, 0, 1}, Port: 42446}, conn.RemoteAddr())
+}
+
+func TestListener_Accept_UntrustedProxy(t *testing.T) {
+	_, private, _ := net.ParseCIDR("10.0.0.0/8")
+	data := append(append([]byte{}, encodedHeaders[0]...), "hello"...)
+
+	inner, dial := testListener(t)
+	listener := NewListener(inner)
+	listener.TrustedProxies = []net.IPNet{*private}
+	defer listener.Close()
+
+	client := dial(data)
+	defer client.Close()
+
+	conn, err := listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, ErrUntrustedProxy, err)
+	assert.Equal(t, client.LocalAddr(), conn.RemoteAddr())
+
+	// Without required header, the header is discarded without honoring it
+	listener.AllowMissingHeader = true
+
+	client = dial(data)
+	defer client.Close()
+
+	conn, err = listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	assert.Equal(t, client.LocalAddr(), conn.RemoteAddr())
+
+	header, err := conn.(*Conn).Header()
+	assert.Nil(t, err)
+	assert.Nil(t, header)
+
+	received := make([]byte, 5)
+	_, err = io.ReadFull(conn, received)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(received))
+
+	// Connections without header are still passed through as is
+	client = dial([]byte("GET / HTTP/1.1\r\n"))
+	defer client.Close()
+
+	conn, err = listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	received = make([]byte, 16)
+	_, err = io.ReadFull(conn, received)
+	assert.Nil(t, err)
+	assert.Equal(t, "GET / HTTP/1.1\r\n", string(received))
+
+	// Malformed header is rejected like from a trusted peer
+	malformed := append([]byte{}, data...)
+	malformed[12] = 0x31
+	client = dial(malformed)
+	defer client.Close()
+
+	conn, err = listener.Accept()
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	_, err = conn.Read(make([]byte, 1))
+	assert.IsType(t, &UnsupportedVersionError{}, err)
+}
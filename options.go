@@ -0,0 +1,79 @@
+package haproxy
+
+import "net"
+
+// DefaultMaxHeaderLength is the limit on the length declared by a header, that
+// is used when ReadOptions.MaxHeaderLength is not set. It leaves plenty of
+// room for addresses and TLVs sent by real proxies.
+const DefaultMaxHeaderLength AddressLength = 4096
+
+// ReadOptions control how Header.ReadFromWithOptions parses a header. The zero
+// value parses headers the same way as Header.ReadFrom.
+type ReadOptions struct {
+	// VerifyChecksum enables verification of the CRC32c checksum if the header
+	// contains a TLVTypeCRC32C TLV. A mismatch is reported as ChecksumError.
+	// Headers without the checksum TLV are accepted, use Header.CRC32C to
+	// require its presence.
+	VerifyChecksum bool
+
+	// AddressRewriter, if set, is called with the decoded source and
+	// destination addresses before the header is returned, and the addresses
+	// it returns are stored in the header instead. It allows to mask or remap
+	// client addresses, e.g. for privacy-preserving logging, so that the
+	// original ones never leave the parser. IPv6 headers stay IPv6 even if
+	// IPv4 or IPv4-mapped addresses are returned, while IPv4 headers become
+	// IPv6 ones if IPv6 addresses are returned, since they can not be
+	// represented otherwise.
+	AddressRewriter func(src, dst net.Addr) (net.Addr, net.Addr)
+
+	// RejectTLVs makes headers that contain any data after the addresses
+	// fail with ErrUnexpectedTLVs, for receivers that do not expect
+	// extensions at all.
+	RejectTLVs bool
+
+	// MaxHeaderLength limits the length of addresses and TLVs declared by the
+	// header. Headers that declare more are rejected with HeaderTooLongError
+	// before anything is allocated for them, so that a hostile sender can not
+	// make the receiver allocate up to 64 KiB per connection. If zero,
+	// DefaultMaxHeaderLength is used.
+	MaxHeaderLength AddressLength
+
+	// Streaming makes the parser read addresses and TLVs field by field, as
+	// it parses them. By default, they are read with a single call once the
+	// declared length is known, which saves system calls when reading right
+	// from a connection.
+	Streaming bool
+
+	// StrictMode makes PROXY headers with unspecified address family or
+	// transport protocol fail with ErrUnspecifiedProtocol. The specification
+	// allows them, asking receivers to use the real connection endpoints,
+	// but receivers that always expect addresses may prefer to fail fast on
+	// such senders. LOCAL headers are not affected.
+	StrictMode bool
+
+	// AllowUnsupportedProtocol makes headers with a combination of address
+	// family and transport protocol this package can not decode, e.g.
+	// AddressFamilyUNSPEC with data, be accepted instead of failing with
+	// TransportProtocolError. The specification lets receivers accept such
+	// connections and use the real connection endpoints instead. The header
+	// is fully consumed and returned with its command and Header.Unsupported
+	// set, but without address and TLVs, so that Header.UnknownAddresses
+	// reports true for PROXY ones.
+	AllowUnsupportedProtocol bool
+}
+
+// WriteOptions control how Header.WriteToWithOptions serializes a header. The
+// zero value produces the same output as Header.WriteTo.
+type WriteOptions struct {
+	// ProtocolVersionOverride, if non-zero, is written to the version nibble
+	// instead of ProtocolVersion. It exists solely for conformance testing,
+	// e.g. to check that a receiver rejects a version 3 header, and must never
+	// be used to talk to real receivers. Only the values up to 0xF fit in
+	// the nibble.
+	ProtocolVersionOverride byte
+
+	// Checksum appends a TLVTypeCRC32C TLV with CRC32c checksum of the whole
+	// header, replacing any checksum TLVs already present in the header.
+	// LOCAL headers carry no TLVs, so they are written without checksum.
+	Checksum bool
+}
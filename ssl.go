@@ -0,0 +1,175 @@
+package haproxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// SSLClient is a bit field in the beginning of TLVTypeSSL TLV describing
+// the client connection.
+type SSLClient byte
+
+const (
+	// SSLClientSSL indicates that the client connected over SSL/TLS.
+	SSLClientSSL SSLClient = 0x01
+
+	// SSLClientCERTCONN indicates that the client provided a certificate over
+	// the current connection.
+	SSLClientCERTCONN SSLClient = 0x02
+
+	// SSLClientCERTSESS indicates that the client provided a certificate at
+	// least once over the TLS session this connection belongs to.
+	SSLClientCERTSESS SSLClient = 0x04
+)
+
+const (
+	// TLVTypeSSLVERSION is a sub-TLV of TLVTypeSSL holding the US-ASCII string
+	// representation of the TLS version, e.g. "TLSv1.3".
+	TLVTypeSSLVERSION TLVType = 0x21
+
+	// TLVTypeSSLCN is a sub-TLV of TLVTypeSSL holding the UTF-8 string
+	// representation of the Common Name field of the client certificate's
+	// Distinguished Name.
+	TLVTypeSSLCN TLVType = 0x22
+
+	// TLVTypeSSLCIPHER is a sub-TLV of TLVTypeSSL holding the US-ASCII string
+	// name of the used cipher, e.g. "ECDHE-RSA-AES128-GCM-SHA256".
+	TLVTypeSSLCIPHER TLVType = 0x23
+
+	// TLVTypeSSLSIGALG is a sub-TLV of TLVTypeSSL holding the US-ASCII string
+	// name of the algorithm used to sign the certificate presented by the
+	// frontend, e.g. "SHA256".
+	TLVTypeSSLSIGALG TLVType = 0x24
+
+	// TLVTypeSSLKEYALG is a sub-TLV of TLVTypeSSL holding the US-ASCII string
+	// name of the algorithm used to generate the key of the certificate
+	// presented by the frontend, e.g. "RSA2048".
+	TLVTypeSSLKEYALG TLVType = 0x25
+)
+
+// SSLInfo is the contents of TLVTypeSSL TLV. Sub-TLVs of types 0x21 to 0x25
+// (TLVTypeSSLVERSION, TLVTypeSSLCN, TLVTypeSSLCIPHER, TLVTypeSSLSIGALG and
+// TLVTypeSSLKEYALG) are decoded into the typed fields, all other sub-TLVs are
+// kept in RawSubTLVs.
+type SSLInfo struct {
+	// Client is a bit field describing the client connection.
+	Client SSLClient
+
+	// Verify is zero if the client presented a certificate and it was
+	// successfully verified, and non-zero otherwise.
+	Verify uint32
+
+	// Version is the TLS version, e.g. "TLSv1.3".
+	Version string
+
+	// ClientCertCN is the Common Name of the client certificate subject.
+	ClientCertCN string
+
+	// Cipher is the name of the cipher, e.g. "ECDHE-RSA-AES256-GCM-SHA384".
+	Cipher string
+
+	// SignatureAlgorithm is the algorithm used to sign the certificate
+	// presented by the proxy, e.g. "SHA256".
+	SignatureAlgorithm string
+
+	// KeyAlgorithm is the algorithm used to generate the key of the
+	// certificate presented by the proxy, e.g. "RSA2048".
+	KeyAlgorithm string
+
+	// RawSubTLVs holds sub-TLVs of unknown types in the order they were sent,
+	// such as vendor extensions carrying the full client certificate DN or its
+	// fingerprint. Their values refer to the TLV value of the header.
+	RawSubTLVs []TLV
+}
+
+// HasSSL reports whether the client connected over SSL/TLS.
+func (i SSLInfo) HasSSL() bool {
+	return i.Client&SSLClientSSL != 0
+}
+
+// ClientCertPresent reports whether the client presented a certificate,
+// either over this connection or earlier in the same TLS session.
+func (i SSLInfo) ClientCertPresent() bool {
+	return i.Client&(SSLClientCERTCONN|SSLClientCERTSESS) != 0
+}
+
+// Verified reports whether the client presented a certificate over TLS and
+// the proxy verified it successfully, i.e. whether the identity from the
+// certificate, like ClientCertCN, can be trusted.
+func (i SSLInfo) Verified() bool {
+	return i.HasSSL() && i.ClientCertPresent() && i.Verify == 0
+}
+
+// ErrNoSSL is returned when SSL information is requested, but the header
+// contains no TLVTypeSSL TLV.
+var ErrNoSSL = errors.New("header contains no SSL TLV")
+
+// ClientCertVerified reports whether the client presented a certificate,
+// either on this connection or earlier in the same TLS session, and the
+// proxy verified it successfully. ErrNoSSL is returned if the header has no
+// SSL information, which allows to tell apart connections that did not use
+// TLS from the ones that did, but presented no valid certificate.
+func (h Header) ClientCertVerified() (bool, error) {
+	tlv, ok := h.findTLV(TLVTypeSSL)
+	if !ok {
+		return false, ErrNoSSL
+	}
+
+	if len(tlv.Value) < 5 {
+		return false, fmt.Errorf("malformed SSL TLV: expected at least 5 bytes, but got %d", len(tlv.Value))
+	}
+
+	info := SSLInfo{
+		Client: SSLClient(tlv.Value[0]),
+		Verify: binary.BigEndian.Uint32(tlv.Value[1:5]),
+	}
+
+	return info.Verified(), nil
+}
+
+// SSL returns the information about the TLS session between the client and
+// the proxy from TLVTypeSSL TLV. It returns false if there is no such TLV, or
+// it is malformed. String sub-TLVs are returned without trailing NUL bytes,
+// which some senders include.
+func (h Header) SSL() (*SSLInfo, bool) {
+	tlv, ok := h.findTLV(TLVTypeSSL)
+	if !ok || len(tlv.Value) < 5 {
+		return nil, false
+	}
+
+	subTLVs, err := ParseSubTLVs(tlv.Value[5:])
+	if err != nil {
+		return nil, false
+	}
+
+	info := &SSLInfo{
+		Client: SSLClient(tlv.Value[0]),
+		Verify: binary.BigEndian.Uint32(tlv.Value[1:5]),
+	}
+
+	for _, subTLV := range subTLVs {
+		switch subTLV.Type {
+		case TLVTypeSSLVERSION:
+			info.Version = trimNUL(subTLV.Value)
+		case TLVTypeSSLCN:
+			info.ClientCertCN = trimNUL(subTLV.Value)
+		case TLVTypeSSLCIPHER:
+			info.Cipher = trimNUL(subTLV.Value)
+		case TLVTypeSSLSIGALG:
+			info.SignatureAlgorithm = trimNUL(subTLV.Value)
+		case TLVTypeSSLKEYALG:
+			info.KeyAlgorithm = trimNUL(subTLV.Value)
+		default:
+			info.RawSubTLVs = append(info.RawSubTLVs, subTLV)
+		}
+	}
+
+	return info, true
+}
+
+// trimNUL converts a string sub-TLV value, dropping NUL terminators.
+func trimNUL(value []byte) string {
+	return string(bytes.TrimRight(value, "\x00"))
+}
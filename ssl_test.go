@@ -0,0 +1,141 @@
+package haproxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader_ClientCertVerified(t *testing.T) {
+	tests := []struct {
+		value    []byte
+		verified bool
+	}{
+		{[]byte{0x07, 0x00, 0x00, 0x00, 0x00}, true},  // Certificate on connection and session, verified
+		{[]byte{0x03, 0x00, 0x00, 0x00, 0x00}, true},  // Certificate on connection, verified
+		{[]byte{0x05, 0x00, 0x00, 0x00, 0x00}, true},  // Certificate on session, verified
+		{[]byte{0x03, 0x00, 0x00, 0x00, 0x15}, false}, // Certificate verification failed
+		{[]byte{0x01, 0x00, 0x00, 0x00, 0x00}, false}, // No certificate
+		{[]byte{0x02, 0x00, 0x00, 0x00, 0x00}, false}, // Not over TLS
+	}
+
+	for _, test := range tests {
+		header := Header{TLVs: []TLV{{TLVTypeSSL, test.value}}}
+		verified, err := header.ClientCertVerified()
+		assert.Nil(t, err)
+		assert.Equal(t, test.verified, verified, test.value)
+	}
+}
+
+func TestHeader_ClientCertVerified_NoSSL(t *testing.T) {
+	_, err := Header{}.ClientCertVerified()
+	assert.Equal(t, ErrNoSSL, err)
+
+	_, err = Header{TLVs: []TLV{{TLVTypeSSL, []byte{0x07}}}}.ClientCertVerified()
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrNoSSL, err)
+}
+
+func TestHeader_SSL(t *testing.T) {
+	value := []byte{
+		0x07, 0x00, 0x00, 0x00, 0x00, // Client flags and verify result
+		0x21, 0x00, 0x07, 'T', 'L', 'S', 'v', '1', '.', '3', // Version
+		0x22, 0x00, 0x06, 'c', 'l', 'i', 'e', 'n', 't', // Common Name
+		0x23, 0x00, 0x16, 'T', 'L', 'S', '_', 'A', 'E', 'S', '_', '1', '2', '8', '_', 'G', 'C', 'M', '_', 'S', 'H', 'A', '2', '5', '6', // Cipher
+		0x24, 0x00, 0x06, 'S', 'H', 'A', '2', '5', '6', // Signature algorithm
+		0x25, 0x00, 0x07, 'R', 'S', 'A', '2', '0', '4', '8', // Key algorithm
+		0x2F, 0x00, 0x01, 0x42, // Unknown sub-TLV
+	}
+
+	info, ok := Header{TLVs: []TLV{{TLVTypeSSL, value}}}.SSL()
+	assert.True(t, ok)
+	assert.Equal(t, &SSLInfo{
+		Client:             SSLClientSSL | SSLClientCERTCONN | SSLClientCERTSESS,
+		Verify:             0,
+		Version:            "TLSv1.3",
+		ClientCertCN:       "client",
+		Cipher:             "TLS_AES_128_GCM_SHA256",
+		SignatureAlgorithm: "SHA256",
+		KeyAlgorithm:       "RSA2048",
+		RawSubTLVs:         []TLV{{0x2F, []byte{0x42}}},
+	}, info)
+}
+
+func TestHeader_SSL_RawSubTLVs(t *testing.T) {
+	value := []byte{
+		0x05, 0x00, 0x00, 0x00, 0x00, // Client flags and verify result
+		0xE0, 0x00, 0x0B, 'C', 'N', '=', 'c', 'l', 'i', 'e', 'n', 't', ',', 'O', // Vendor DN
+		0x22, 0x00, 0x06, 'c', 'l', 'i', 'e', 'n', 't', // Common Name
+		0xE1, 0x00, 0x04, 0xDE, 0xAD, 0xBE, 0xEF, // Vendor fingerprint
+		0x21, 0x00, 0x07, 'T', 'L', 'S', 'v', '1', '.', '3', // Version
+		0xE0, 0x00, 0x00, // Repeated vendor sub-TLV without value
+	}
+
+	info, ok := Header{TLVs: []TLV{{TLVTypeSSL, value}}}.SSL()
+	assert.True(t, ok)
+	assert.Equal(t, "client", info.ClientCertCN)
+	assert.Equal(t, "TLSv1.3", info.Version)
+	assert.Equal(t, []TLV{
+		{0xE0, []byte("CN=client,O")},
+		{0xE1, []byte{0xDE, 0xAD, 0xBE, 0xEF}},
+		{0xE0, []byte{}},
+	}, info.RawSubTLVs)
+}
+
+func TestHeader_SSL_Invalid(t *testing.T) {
+	_, ok := Header{}.SSL()
+	assert.False(t, ok)
+
+	_, ok = Header{TLVs: []TLV{{TLVTypeSSL, []byte{0x07, 0x00}}}}.SSL()
+	assert.False(t, ok)
+
+	// Sub-TLV declares more bytes than there are
+	_, ok = Header{TLVs: []TLV{{TLVTypeSSL, []byte{0x07, 0x00, 0x00, 0x00, 0x00, 0x21, 0x00, 0x07, 'T'}}}}.SSL()
+	assert.False(t, ok)
+}
+
+func TestHeader_SSL_TrailingNUL(t *testing.T) {
+	value := []byte{
+		0x05, 0x00, 0x00, 0x00, 0x00, // Client flags and verify result
+		0x21, 0x00, 0x08, 'T', 'L', 'S', 'v', '1', '.', '2', 0x00, // Version
+		0x22, 0x00, 0x09, 'c', 'l', 'i', 'e', 'n', 't', 0x00, 0x00, 0x00, // Common Name
+		0x23, 0x00, 0x1C, 'E', 'C', 'D', 'H', 'E', '-', 'R', 'S', 'A', '-', 'A', 'E', 'S', '2', '5', '6', '-',
+		'G', 'C', 'M', '-', 'S', 'H', 'A', '3', '8', '4', 0x00, // Cipher
+	}
+
+	info, ok := Header{TLVs: []TLV{{TLVTypeSSL, value}}}.SSL()
+	assert.True(t, ok)
+	assert.Equal(t, "TLSv1.2", info.Version)
+	assert.Equal(t, "client", info.ClientCertCN)
+	assert.Equal(t, "ECDHE-RSA-AES256-GCM-SHA384", info.Cipher)
+	assert.Empty(t, info.SignatureAlgorithm)
+	assert.Empty(t, info.KeyAlgorithm)
+	assert.Nil(t, info.RawSubTLVs)
+}
+
+func TestSSLInfo_Verified(t *testing.T) {
+	tests := []struct {
+		value    []byte
+		ssl      bool
+		present  bool
+		verified bool
+	}{
+		{[]byte{0x07, 0x00, 0x00, 0x00, 0x00}, true, true, true},    // Certificate on connection and session, verified
+		{[]byte{0x03, 0x00, 0x00, 0x00, 0x00}, true, true, true},    // Certificate on connection, verified
+		{[]byte{0x05, 0x00, 0x00, 0x00, 0x00}, true, true, true},    // Certificate on session, verified
+		{[]byte{0x03, 0x00, 0x00, 0x00, 0x15}, true, true, false},   // Certificate verification failed
+		{[]byte{0x01, 0x00, 0x00, 0x00, 0x00}, true, false, false},  // No certificate
+		{[]byte{0x00, 0x00, 0x00, 0x00, 0x00}, false, false, false}, // Not over TLS
+	}
+
+	for _, test := range tests {
+		info, ok := Header{TLVs: []TLV{{TLVTypeSSL, test.value}}}.SSL()
+		assert.True(t, ok)
+		assert.Equal(t, test.ssl, info.HasSSL(), test.value)
+		assert.Equal(t, test.present, info.ClientCertPresent(), test.value)
+		assert.Equal(t, test.verified, info.Verified(), test.value)
+	}
+
+	info, _ := Header{TLVs: []TLV{{TLVTypeSSL, []byte{0x03, 0x00, 0x00, 0x00, 0x15}}}}.SSL()
+	assert.Equal(t, uint32(0x15), info.Verify)
+}
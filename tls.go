@@ -0,0 +1,28 @@
+package haproxy
+
+import (
+	"crypto/tls"
+	"net"
+)
+
+// AcceptProxyThenTLS reads a PROXY header (either version) sent in plaintext
+// on rawConn and then performs the server side of TLS handshake over the rest
+// of the connection. This is the setup used when the proxy passes TLS through
+// and prepends the header on the raw socket.
+//
+// If the handshake fails, the already parsed header is returned along with
+// the error, so that the real client address can still be logged. Closing
+// rawConn on error is up to the caller.
+func AcceptProxyThenTLS(rawConn net.Conn, cfg *tls.Config) (*Header, *tls.Conn, error) {
+	header, err := ReadHeader(rawConn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn := tls.Server(rawConn, cfg)
+	if err := conn.Handshake(); err != nil {
+		return header, nil, err
+	}
+
+	return header, conn, nil
+}
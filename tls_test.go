@@ -0,0 +1,73 @@
+package haproxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestAcceptProxyThenTLS(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		_, _ = headers[0].WriteTo(client)
+
+		conn := tls.Client(client, &tls.Config{InsecureSkipVerify: true})
+		_, _ = conn.Write([]byte("hello"))
+	}()
+
+	cfg := &tls.Config{Certificates: []tls.Certificate{testCertificate(t)}}
+	header, conn, err := AcceptProxyThenTLS(server, cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, CommandPROXY, header.Command)
+	assert.Equal(t, headers[0].ProxyAddress.(*IPv4Address).SourceAddr, header.ProxyAddress.(*IPv4Address).SourceAddr)
+
+	data := make([]byte, 5)
+	_, err = io.ReadFull(conn, data)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", string(data))
+}
+
+func TestAcceptProxyThenTLS_NoHeader(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		conn := tls.Client(client, &tls.Config{InsecureSkipVerify: true})
+		_ = conn.Handshake()
+	}()
+
+	cfg := &tls.Config{Certificates: []tls.Certificate{testCertificate(t)}}
+	_, _, err := AcceptProxyThenTLS(server, cfg)
+	assert.IsType(t, &ProxyProtocolError{}, err)
+}
@@ -0,0 +1,246 @@
+package haproxy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+type TLVType byte
+
+const (
+	// TLVTypeALPN application-layer protocol negotiation (ALPN). It is a byte
+	// sequence defined by the RFC7301 (e.g. "h2" or "http/1.1").
+	TLVTypeALPN TLVType = 0x01
+
+	// TLVTypeAUTHORITY contains the host name value passed by the client, as an
+	// UTF8-encoded string. In case of TLS being used on the client connection,
+	// this is the exact copy of the "server_name" extension.
+	TLVTypeAUTHORITY TLVType = 0x02
+
+	// TLVTypeCRC32C is a 32-bit number storing the CRC32c checksum of the PROXY
+	// protocol header, computed with the checksum field itself set to zero.
+	TLVTypeCRC32C TLVType = 0x03
+
+	// TLVTypeNOOP should be ignored when parsed. It may be used to align
+	// the header or to reserve space for later rewriting.
+	TLVTypeNOOP TLVType = 0x04
+
+	// TLVTypeUNIQUEID is an opaque byte sequence of up to 128 bytes generated
+	// by the upstream proxy that uniquely identifies the connection.
+	TLVTypeUNIQUEID TLVType = 0x05
+
+	// TLVTypeSSL carries information about the TLS session between the client
+	// and the proxy, including its own sub-TLVs.
+	TLVTypeSSL TLVType = 0x20
+
+	// TLVTypeNETNS defines the value as the US-ASCII string representation of
+	// the namespace's name.
+	TLVTypeNETNS TLVType = 0x30
+)
+
+// TLV is a Type-Length-Value vector that may follow addresses in a version 2
+// header. On the wire its type takes one byte and length takes two bytes in
+// network byte order, followed by the value itself.
+type TLV struct {
+	Type  TLVType
+	Value []byte
+}
+
+// MaxUniqueIDLength is the maximum length of TLVTypeUNIQUEID value.
+const MaxUniqueIDLength = 128
+
+// ReadFrom reads a single TLV from r. It returns io.EOF only if there is no
+// data at all, and io.ErrUnexpectedEOF if r ends in the middle of the TLV, so
+// that TLVs can be read one by one until io.EOF.
+func (t *TLV) ReadFrom(r io.Reader) (m int64, err error) {
+	var prefix [3]byte
+	n, err := io.ReadFull(r, prefix[:])
+	m += int64(n)
+	if err != nil {
+		return m, err
+	}
+
+	value := make([]byte, binary.BigEndian.Uint16(prefix[1:3]))
+	n, err = io.ReadFull(r, value)
+	m += int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	if err != nil {
+		return m, err
+	}
+
+	t.Type = TLVType(prefix[0])
+	t.Value = value
+	return m, nil
+}
+
+func (t TLV) WriteTo(w io.Writer) (m int64, err error) {
+	data, err := t.appendTo(nil)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := writeFull(w, data)
+	return int64(n), err
+}
+
+func (t TLV) appendTo(dst []byte) ([]byte, error) {
+	err := t.validate()
+	if err != nil {
+		return dst, err
+	}
+
+	dst = append(dst, byte(t.Type), byte(len(t.Value)>>8), byte(len(t.Value)))
+	return append(dst, t.Value...), nil
+}
+
+// TLVTooLargeError is returned when a TLV value is longer than its type
+// allows. Every value must fit in the two-byte length field, and some types,
+// like TLVTypeUNIQUEID, are limited further.
+type TLVTooLargeError struct {
+	Type      TLVType
+	Length    int
+	MaxLength int
+}
+
+func (e TLVTooLargeError) Error() string {
+	return fmt.Sprintf("value of TLV type %x is %d bytes long, but at most %d bytes are allowed", e.Type, e.Length, e.MaxLength)
+}
+
+// validate checks that the value fits in the length field, and that values of
+// the types with limited length are not too long.
+func (t TLV) validate() error {
+	if len(t.Value) > math.MaxUint16 {
+		return &TLVTooLargeError{Type: t.Type, Length: len(t.Value), MaxLength: math.MaxUint16}
+	}
+
+	if t.Type == TLVTypeUNIQUEID && len(t.Value) > MaxUniqueIDLength {
+		return &TLVTooLargeError{Type: t.Type, Length: len(t.Value), MaxLength: MaxUniqueIDLength}
+	}
+
+	return nil
+}
+
+// ParseSubTLVs splits a TLV value into sub-TLVs, for the types that embed
+// them using the same encoding, such as TLVTypeSSL after its fixed fields.
+// Sub-TLVs that declare more bytes than there are left in the value are
+// rejected. Returned values refer to data.
+func ParseSubTLVs(data []byte) ([]TLV, error) {
+	return parseTLVs(data)
+}
+
+// parseTLVs splits data into TLV vectors. Returned values refer to data.
+func parseTLVs(data []byte) ([]TLV, error) {
+	var tlvs []TLV
+
+	for len(data) > 0 {
+		if len(data) < 3 {
+			return nil, fmt.Errorf("malformed TLV: expected at least 3 bytes, but only %d left", len(data))
+		}
+
+		length := int(binary.BigEndian.Uint16(data[1:3]))
+		if 3+length > len(data) {
+			return nil, fmt.Errorf(
+				"malformed TLV: type %x declares length %d, but only %d bytes left", data[0], length, len(data)-3,
+			)
+		}
+
+		tlvs = append(tlvs, TLV{Type: TLVType(data[0]), Value: data[3 : 3+length]})
+		data = data[3+length:]
+	}
+
+	return tlvs, nil
+}
+
+// findTLV returns the first TLV of the given type.
+func (h Header) findTLV(t TLVType) (TLV, bool) {
+	for _, tlv := range h.TLVs {
+		if tlv.Type == t {
+			return tlv, true
+		}
+	}
+
+	return TLV{}, false
+}
+
+// setTLV replaces the value of the first TLV of the given type, and removes
+// the other ones. The TLV is appended if there is no such TLV yet.
+func (h *Header) setTLV(tlv TLV) {
+	tlvs := h.TLVs[:0:0]
+	found := false
+	for _, existing := range h.TLVs {
+		if existing.Type != tlv.Type {
+			tlvs = append(tlvs, existing)
+		} else if !found {
+			tlvs = append(tlvs, tlv)
+			found = true
+		}
+	}
+
+	if !found {
+		tlvs = append(tlvs, tlv)
+	}
+
+	h.TLVs = tlvs
+}
+
+// GetTLV returns the value of the first TLV of the given type. The returned
+// slice refers to the TLV value.
+func (h Header) GetTLV(t TLVType) ([]byte, bool) {
+	tlv, ok := h.findTLV(t)
+	return tlv.Value, ok
+}
+
+// GetTLVs returns values of all TLVs of the given type in the order they
+// appear in the header.
+func (h Header) GetTLVs(t TLVType) [][]byte {
+	var values [][]byte
+	for _, tlv := range h.TLVs {
+		if tlv.Type == t {
+			values = append(values, tlv.Value)
+		}
+	}
+
+	return values
+}
+
+// RangeTLVs calls fn for every TLV in the order they appear in the header,
+// including duplicates and types unknown to this package, until fn returns
+// false. Values refer to the header, so fn must copy them to keep them.
+func (h Header) RangeTLVs(fn func(t TLV) bool) {
+	for _, tlv := range h.TLVs {
+		if !fn(tlv) {
+			return
+		}
+	}
+}
+
+// SetTLV sets the value of TLV of the given type, replacing all existing TLVs
+// of this type. The value must fit in the length field.
+func (h *Header) SetTLV(t TLVType, value []byte) error {
+	tlv := TLV{Type: t, Value: value}
+	err := tlv.validate()
+	if err != nil {
+		return err
+	}
+
+	h.setTLV(tlv)
+	return nil
+}
+
+// AddTLV appends TLV of the given type, keeping existing TLVs of this type.
+// The value must fit in the length field.
+func (h *Header) AddTLV(t TLVType, value []byte) error {
+	tlv := TLV{Type: t, Value: value}
+	err := tlv.validate()
+	if err != nil {
+		return err
+	}
+
+	h.TLVs = append(h.TLVs, tlv)
+	return nil
+}
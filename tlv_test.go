@@ -0,0 +1,268 @@
+package haproxy
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader_ReadFrom_TLVs(t *testing.T) {
+	data := []byte{
+		0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x0d, 0x0a, 0x51, 0x55, 0x49, 0x54, 0x0a, 0x21, 0x11, 0x00, 0x22,
+		0x7f, 0x00, 0x00, 0x01, 0x7f, 0x00, 0x00, 0x01, 0xa5, 0xce, 0x05, 0x3a, 0x01, 0x00, 0x02, 0x68,
+		0x32, 0x02, 0x00, 0x0b, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d, 0x04,
+		0x00, 0x00, 0x47, 0x45, 0x54,
+	}
+
+	reader := bytes.NewReader(data)
+
+	var header Header
+	n, err := header.ReadFrom(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, 50, int(n))
+	assert.Equal(t, []TLV{
+		{TLVTypeALPN, []byte("h2")},
+		{TLVTypeAUTHORITY, []byte("example.com")},
+		{TLVTypeNOOP, []byte{}},
+	}, header.TLVs)
+
+	// Application data that follows TLVs must be left in the stream
+	assert.Equal(t, 3, reader.Len())
+}
+
+func TestHeader_ReadFrom_MalformedTLVs(t *testing.T) {
+	malformed := [][]byte{
+		{ // TLV value extends past the declared length
+			0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x0d, 0x0a, 0x51, 0x55, 0x49, 0x54, 0x0a, 0x21, 0x11, 0x00, 0x10,
+			0x7f, 0x00, 0x00, 0x01, 0x7f, 0x00, 0x00, 0x01, 0xa5, 0xce, 0x05, 0x3a, 0x01, 0x00, 0x02, 0x68,
+			0x32,
+		},
+		{ // Incomplete TLV header
+			0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x0d, 0x0a, 0x51, 0x55, 0x49, 0x54, 0x0a, 0x21, 0x11, 0x00, 0x0e,
+			0x7f, 0x00, 0x00, 0x01, 0x7f, 0x00, 0x00, 0x01, 0xa5, 0xce, 0x05, 0x3a, 0x01, 0x00,
+		},
+		{ // Declared length is shorter than IPv4 addresses
+			0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x0d, 0x0a, 0x51, 0x55, 0x49, 0x54, 0x0a, 0x21, 0x11, 0x00, 0x08,
+			0x7f, 0x00, 0x00, 0x01, 0x7f, 0x00, 0x00, 0x01,
+		},
+	}
+
+	for _, data := range malformed {
+		var header Header
+		_, err := header.ReadFrom(bytes.NewReader(data))
+		assert.NotNil(t, err)
+	}
+}
+
+func TestParseTLVs(t *testing.T) {
+	tlvs, err := parseTLVs(nil)
+	assert.Nil(t, err)
+	assert.Empty(t, tlvs)
+
+	tlvs, err = parseTLVs([]byte{0x05, 0x00, 0x01, 0xff, 0x05, 0x00, 0x00})
+	assert.Nil(t, err)
+	assert.Equal(t, []TLV{{TLVTypeUNIQUEID, []byte{0xff}}, {TLVTypeUNIQUEID, []byte{}}}, tlvs)
+
+	_, err = parseTLVs([]byte{0x05, 0x00, 0x02, 0xff})
+	assert.NotNil(t, err)
+}
+
+func TestHeader_WriteTo_TLVs(t *testing.T) {
+	header := Header{
+		Command:      CommandPROXY,
+		ProxyAddress: headers[0].ProxyAddress,
+		TLVs: []TLV{
+			{TLVTypeALPN, []byte("h2")},
+			{TLVTypeAUTHORITY, []byte("example.com")},
+		},
+	}
+
+	buffer := &bytes.Buffer{}
+	n, err := header.WriteTo(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, 47, int(n))
+	assert.Equal(t, []byte{0x00, 0x1f}, buffer.Bytes()[14:16])
+
+	var decoded Header
+	m, err := decoded.ReadFrom(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, n, m)
+	assert.Equal(t, header.ProxyAddress, decoded.ProxyAddress)
+	assert.Equal(t, header.TLVs, decoded.TLVs)
+}
+
+func TestTLV_WriteTo(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	n, err := TLV{TLVTypeALPN, []byte("h2")}.WriteTo(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, int(n))
+	assert.Equal(t, []byte{0x01, 0x00, 0x02, 0x68, 0x32}, buffer.Bytes())
+
+	_, err = TLV{TLVTypeALPN, make([]byte, 70000)}.WriteTo(&bytes.Buffer{})
+	assert.NotNil(t, err)
+}
+
+func TestHeader_ReadFromWithOptions_RejectTLVs(t *testing.T) {
+	var header Header
+	_, err := header.ReadFromWithOptions(bytes.NewReader(checksumHeader), ReadOptions{RejectTLVs: true})
+	assert.Equal(t, ErrUnexpectedTLVs, err)
+
+	_, err = header.ReadFromWithOptions(bytes.NewReader(encodedHeaders[0]), ReadOptions{RejectTLVs: true})
+	assert.Nil(t, err)
+}
+
+func TestHeader_GetTLV(t *testing.T) {
+	header := Header{TLVs: []TLV{{0xE0, []byte("first")}, {TLVTypeALPN, []byte("h2")}, {0xE0, []byte("second")}}}
+
+	value, ok := header.GetTLV(0xE0)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("first"), value)
+	assert.Equal(t, [][]byte{[]byte("first"), []byte("second")}, header.GetTLVs(0xE0))
+
+	_, ok = header.GetTLV(TLVTypeNOOP)
+	assert.False(t, ok)
+	assert.Nil(t, header.GetTLVs(TLVTypeNOOP))
+}
+
+func TestHeader_SetTLV(t *testing.T) {
+	header := Header{TLVs: []TLV{{0xE0, []byte("first")}, {TLVTypeALPN, []byte("h2")}, {0xE0, []byte("second")}}}
+
+	assert.Nil(t, header.SetTLV(0xE0, []byte("third")))
+	assert.Equal(t, []TLV{{0xE0, []byte("third")}, {TLVTypeALPN, []byte("h2")}}, header.TLVs)
+
+	assert.Nil(t, header.AddTLV(0xE0, []byte("fourth")))
+	assert.Equal(t, [][]byte{[]byte("third"), []byte("fourth")}, header.GetTLVs(0xE0))
+
+	assert.NotNil(t, header.SetTLV(0xE1, make([]byte, 70000)))
+	assert.NotNil(t, header.AddTLV(0xE1, make([]byte, 70000)))
+	assert.Len(t, header.TLVs, 3)
+}
+
+func TestParseSubTLVs(t *testing.T) {
+	tlvs, err := ParseSubTLVs([]byte{0x21, 0x00, 0x02, 'h', 'i', 0x2F, 0x00, 0x00})
+	assert.Nil(t, err)
+	assert.Equal(t, []TLV{{0x21, []byte("hi")}, {0x2F, []byte{}}}, tlvs)
+
+	// Declared length overruns the parent value
+	_, err = ParseSubTLVs([]byte{0x21, 0x00, 0x03, 'h', 'i'})
+	assert.NotNil(t, err)
+}
+
+func TestHeader_ReadFrom_TLVRemainder(t *testing.T) {
+	data := []byte{
+		0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A, // Signature
+		0x21, 0x11, 0x00, 0x1A, // PROXY, TCP over IPv4, length 26
+		0xC0, 0xA8, 0x00, 0x01, 0xC0, 0xA8, 0x00, 0x0B, 0xDC, 0x04, 0x01, 0xBB, // Addresses and ports
+		0x02, 0x00, 0x0B, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm', // Authority TLV
+		'G', 'E', 'T', // Application data
+	}
+
+	for _, opts := range []ReadOptions{{}, {Streaming: true}} {
+		reader := bytes.NewReader(data)
+
+		var header Header
+		n, err := header.ReadFromWithOptions(reader, opts)
+		assert.Nil(t, err)
+		assert.Equal(t, len(data)-3, int(n))
+		assert.Equal(t, []TLV{{TLVTypeAUTHORITY, []byte("example.com")}}, header.TLVs)
+
+		// TLVs must be consumed along with the addresses, leaving only application data
+		rest := make([]byte, reader.Len())
+		_, _ = reader.Read(rest)
+		assert.Equal(t, "GET", string(rest))
+	}
+}
+
+func TestTLVTooLargeError(t *testing.T) {
+	expected := &TLVTooLargeError{Type: 0xE1, Length: 70000, MaxLength: 65535}
+
+	var header Header
+	assert.Equal(t, expected, header.SetTLV(0xE1, make([]byte, 70000)))
+	assert.Equal(t, expected, header.AddTLV(0xE1, make([]byte, 70000)))
+	assert.Nil(t, header.TLVs)
+
+	_, err := TLV{0xE1, make([]byte, 70000)}.WriteTo(&bytes.Buffer{})
+	assert.Equal(t, expected, err)
+	assert.Equal(t, "value of TLV type e1 is 70000 bytes long, but at most 65535 bytes are allowed", err.Error())
+
+	// TLVs set directly are checked when writing, and nothing is written
+	header = Header{Command: CommandPROXY, ProxyAddress: headers[0].ProxyAddress, TLVs: []TLV{{0xE1, make([]byte, 70000)}}}
+	buffer := &bytes.Buffer{}
+	_, err = header.WriteTo(buffer)
+	assert.Equal(t, expected, err)
+	assert.Equal(t, 0, buffer.Len())
+
+	err = header.AddUniqueID(make([]byte, MaxUniqueIDLength+1))
+	assert.Equal(t, &TLVTooLargeError{Type: TLVTypeUNIQUEID, Length: MaxUniqueIDLength + 1, MaxLength: MaxUniqueIDLength}, err)
+}
+
+func TestHeader_RangeTLVs(t *testing.T) {
+	data := append(append([]byte{}, encodedHeaders[0]...),
+		0xE7, 0x00, 0x01, 0x01, // Unknown type
+		0x01, 0x00, 0x02, 'h', '2', // ALPN
+		0xE7, 0x00, 0x01, 0x02, // Unknown type again
+	)
+	data[15] += 13
+
+	var header Header
+	_, err := header.ReadFrom(bytes.NewReader(data))
+	assert.Nil(t, err)
+
+	var types []TLVType
+	header.RangeTLVs(func(tlv TLV) bool {
+		types = append(types, tlv.Type)
+		return true
+	})
+	assert.Equal(t, []TLVType{0xE7, TLVTypeALPN, 0xE7}, types)
+
+	var visited []TLV
+	header.RangeTLVs(func(tlv TLV) bool {
+		visited = append(visited, tlv)
+		return tlv.Type != TLVTypeALPN
+	})
+	assert.Equal(t, []TLV{{0xE7, []byte{0x01}}, {TLVTypeALPN, []byte("h2")}}, visited)
+}
+
+func TestTLV_ReadFrom(t *testing.T) {
+	tlvs := []TLV{
+		{TLVTypeALPN, []byte("h2")},
+		{TLVTypeNOOP, []byte{}},
+		{0xE0, bytes.Repeat([]byte{0x42}, 300)},
+	}
+
+	buffer := &bytes.Buffer{}
+	for _, tlv := range tlvs {
+		_, err := tlv.WriteTo(buffer)
+		assert.Nil(t, err)
+	}
+
+	var decoded []TLV
+	for {
+		var tlv TLV
+		n, err := tlv.ReadFrom(buffer)
+		if err == io.EOF {
+			assert.Equal(t, int64(0), n)
+			break
+		}
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3+len(tlv.Value)), n)
+		decoded = append(decoded, tlv)
+	}
+
+	assert.Equal(t, tlvs, decoded)
+}
+
+func TestTLV_ReadFrom_Truncated(t *testing.T) {
+	data := []byte{0x01, 0x00, 0x02, 'h', '2'}
+
+	for length := 1; length < len(data); length++ {
+		tlv := TLV{Type: TLVTypeAUTHORITY}
+		n, err := tlv.ReadFrom(bytes.NewReader(data[:length]))
+		assert.Equal(t, io.ErrUnexpectedEOF, err)
+		assert.Equal(t, int64(length), n)
+		assert.Equal(t, TLV{Type: TLVTypeAUTHORITY}, tlv)
+	}
+}
@@ -0,0 +1,287 @@
+package haproxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// V1Signature is the prefix every version 1 (human-readable) header starts with.
+var V1Signature = []byte("PROXY ")
+
+// V1MaxLength is the maximum length of a version 1 header, including the
+// terminating CRLF, as defined by the specification.
+const V1MaxLength = 107
+
+// ReadFromV1 reads a version 1 header, e.g. "PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n".
+// It never reads past the terminating CRLF, so the reader is left positioned at
+// the first byte of application data.
+//
+// TCP4 and TCP6 lines populate ProxyAddress with IPv4Address and IPv6Address
+// respectively. An UNKNOWN line is accepted as CommandPROXY with a nil
+// ProxyAddress, and everything after the UNKNOWN token is ignored as the
+// specification requires.
+func (h *Header) ReadFromV1(r io.Reader) (m int64, err error) {
+	line := make([]byte, 0, V1MaxLength)
+	b := make([]byte, 1)
+
+	for {
+		if len(line) == V1MaxLength {
+			return m, fmt.Errorf("v1 header is longer than %d bytes or is not terminated with CRLF", V1MaxLength)
+		}
+
+		n, err := io.ReadFull(r, b)
+		m += int64(n)
+		if err == io.EOF {
+			return m, &IncompleteHeaderError{Read: int(m), Expected: int(m) + 1}
+		}
+
+		if err != nil {
+			return m, err
+		}
+
+		line = append(line, b[0])
+
+		// Bail out as soon as it is clear that there is no header, without
+		// consuming the whole line
+		if len(line) == len(V1Signature) && !bytes.Equal(line, V1Signature) {
+			return m, &ProxyProtocolError{V1Signature, line}
+		}
+
+		if b[0] == '\n' {
+			break
+		}
+	}
+
+	if len(line) < len(V1Signature) {
+		return m, &ProxyProtocolError{V1Signature, line}
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return m, fmt.Errorf("v1 header is not terminated with CRLF")
+	}
+
+	fields := strings.Split(string(line[len(V1Signature):len(line)-2]), " ")
+
+	// The receiver must ignore anything presented after the UNKNOWN token
+	if fields[0] == "UNKNOWN" {
+		h.Command = CommandPROXY
+		h.ProxyAddress = nil
+		h.TLVs = nil
+		h.Unsupported = false
+		return
+	}
+
+	if fields[0] != "TCP4" && fields[0] != "TCP6" {
+		return m, fmt.Errorf("unsupported v1 protocol: expected TCP4, TCP6 or UNKNOWN, but got %q", fields[0])
+	}
+
+	if len(fields) != 5 {
+		return m, fmt.Errorf("malformed v1 header: expected 5 fields, but got %d", len(fields))
+	}
+
+	sourceIP, err := parseV1IP(fields[0], fields[1])
+	if err != nil {
+		return m, err
+	}
+
+	destinationIP, err := parseV1IP(fields[0], fields[2])
+	if err != nil {
+		return m, err
+	}
+
+	sourcePort, err := parseV1Port(fields[3])
+	if err != nil {
+		return m, err
+	}
+
+	destinationPort, err := parseV1Port(fields[4])
+	if err != nil {
+		return m, err
+	}
+
+	source := &net.TCPAddr{IP: sourceIP, Port: sourcePort}
+	destination := &net.TCPAddr{IP: destinationIP, Port: destinationPort}
+
+	h.Command = CommandPROXY
+	h.TLVs = nil
+	h.Unsupported = false
+	if fields[0] == "TCP4" {
+		h.ProxyAddress = &IPv4Address{SourceAddr: source, DestinationAddr: destination}
+	} else {
+		h.ProxyAddress = &IPv6Address{SourceAddr: source, DestinationAddr: destination}
+	}
+
+	return
+}
+
+func parseV1IP(protocol, s string) (net.IP, error) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("malformed v1 header: invalid address %q", s)
+	}
+
+	if protocol == "TCP4" {
+		if strings.Contains(s, ":") {
+			return nil, fmt.Errorf("malformed v1 header: expected IPv4 address, but got %q", s)
+		}
+
+		return ip.To4(), nil
+	}
+
+	if !strings.Contains(s, ":") {
+		return nil, fmt.Errorf("malformed v1 header: expected IPv6 address, but got %q", s)
+	}
+
+	return unmapIPv4(ip), nil
+}
+
+func parseV1Port(s string) (int, error) {
+	port, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("malformed v1 header: invalid port %q", s)
+	}
+
+	return int(port), nil
+}
+
+// WriteToV1 writes the header in version 1 format. Only TCP over IPv4 and IPv6
+// can be represented, LOCAL headers and headers without address are written
+// as "PROXY UNKNOWN\r\n". Addresses that WriteTo rejects, such as nil IPs,
+// IPs of the wrong family or ports out of range, are an error as well.
+func (h Header) WriteToV1(w io.Writer) (m int64, err error) {
+	line := "PROXY UNKNOWN\r\n"
+
+	if h.Command == CommandPROXY && h.ProxyAddress != nil {
+		signature, err := h.ProxyAddress.getSignature()
+		if err != nil {
+			return 0, err
+		}
+
+		if signature.TransportProtocol != TransportProtocolSTREAM {
+			return 0, fmt.Errorf("v1 header supports only TCP, but got transport protocol %s", signature.TransportProtocol)
+		}
+
+		source, destination := h.ProxyAddress.getAddresses()
+		sourceAddr, ok := source.(*net.TCPAddr)
+		if !ok {
+			return 0, fmt.Errorf("v1 header supports only TCP, but got source %s", source)
+		}
+
+		destinationAddr, ok := destination.(*net.TCPAddr)
+		if !ok {
+			return 0, fmt.Errorf("v1 header supports only TCP, but got destination %s", destination)
+		}
+
+		length := net.IPv6len
+		switch signature.AddressFamily {
+		case AddressFamilyINET:
+			length = net.IPv4len
+		case AddressFamilyINET6:
+		default:
+			return 0, fmt.Errorf("v1 header supports only IPv4 and IPv6, but got address family %s", signature.AddressFamily)
+		}
+
+		// Addresses and ports are checked like WriteTo does, so that no
+		// malformed line is ever written
+		err = validateV1IP(sourceAddr.IP, length)
+		if err != nil {
+			return 0, err
+		}
+
+		err = validateV1IP(destinationAddr.IP, length)
+		if err != nil {
+			return 0, err
+		}
+
+		sourcePort, err := getPort(sourceAddr)
+		if err != nil {
+			return 0, err
+		}
+
+		destinationPort, err := getPort(destinationAddr)
+		if err != nil {
+			return 0, err
+		}
+
+		if length == net.IPv4len {
+			line = fmt.Sprintf(
+				"PROXY TCP4 %s %s %d %d\r\n",
+				sourceAddr.IP.To4(), destinationAddr.IP.To4(), sourcePort, destinationPort,
+			)
+		} else {
+			line = fmt.Sprintf(
+				"PROXY TCP6 %s %s %d %d\r\n",
+				formatV1IPv6(sourceAddr.IP), formatV1IPv6(destinationAddr.IP), sourcePort, destinationPort,
+			)
+		}
+	}
+
+	n, err := writeFull(w, []byte(line))
+	return int64(n), err
+}
+
+// validateV1IP returns an error if ip cannot be written as length bytes in
+// version 2 format, which also makes it invalid in version 1 format.
+func validateV1IP(ip net.IP, length int) error {
+	var buffer [net.IPv6len]byte
+	_, err := appendIP(buffer[:0], ip, length)
+	return err
+}
+
+// formatV1IPv6 formats IP so that it is always recognized as IPv6, since
+// net.IP.String prints IPv4-mapped addresses in dotted form.
+func formatV1IPv6(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return "::ffff:" + ip4.String()
+	}
+
+	return ip.String()
+}
+
+// MarshalText implements encoding.TextMarshaler and returns the header in
+// version 1 format, as written by WriteToV1, e.g. for configs and test
+// fixtures. Only TCP addresses can be represented this way. LOCAL command and
+// TLVs have no version 1 representation either, so they are an error instead
+// of being silently turned into "PROXY UNKNOWN" or dropped.
+func (h Header) MarshalText() ([]byte, error) {
+	if h.Command != CommandPROXY {
+		return nil, fmt.Errorf("v1 header can carry only PROXY command, but got %s", h.Command)
+	}
+
+	if len(h.TLVs) > 0 {
+		return nil, fmt.Errorf("v1 header can not carry TLVs, but got %d of them", len(h.TLVs))
+	}
+
+	buffer := &bytes.Buffer{}
+	_, err := h.WriteToV1(buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. The text must hold
+// exactly one version 1 header including the trailing CRLF, any bytes that
+// follow it are an error. The header is left unchanged if the text cannot be
+// decoded.
+func (h *Header) UnmarshalText(text []byte) error {
+	reader := bytes.NewReader(text)
+
+	var decoded Header
+	_, err := decoded.ReadFromV1(reader)
+	if err != nil {
+		return err
+	}
+
+	if reader.Len() > 0 {
+		return fmt.Errorf("unexpected %d bytes after the end of header", reader.Len())
+	}
+
+	*h = decoded
+	return nil
+}
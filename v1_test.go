@@ -0,0 +1,292 @@
+package haproxy
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader_ReadFromV1(t *testing.T) {
+	reader := strings.NewReader("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\nGET / HTTP/1.1\r\n")
+
+	var header Header
+	n, err := header.ReadFromV1(reader)
+	assert.Nil(t, err)
+	assert.Equal(t, 47, int(n))
+	assert.Equal(t, CommandPROXY, header.Command)
+
+	addr := header.ProxyAddress.(*IPv4Address)
+	assert.Equal(t, &net.TCPAddr{IP: []byte{192, 168, 0, 1}, Port: 56324}, addr.SourceAddr)
+	assert.Equal(t, &net.TCPAddr{IP: []byte{192, 168, 0, 11}, Port: 443}, addr.DestinationAddr)
+
+	// Application data must be left untouched
+	rest := make([]byte, reader.Len())
+	_, _ = reader.Read(rest)
+	assert.Equal(t, "GET / HTTP/1.1\r\n", string(rest))
+}
+
+func TestHeader_ReadFromV1_IPv6(t *testing.T) {
+	reader := strings.NewReader("PROXY TCP6 2345:425:2ca1::567:5673:23b5 2607:f0d0:1002:51::4 32051 443\r\n")
+
+	var header Header
+	_, err := header.ReadFromV1(reader)
+	assert.Nil(t, err)
+
+	addr := header.ProxyAddress.(*IPv6Address)
+	assert.Equal(t, &net.TCPAddr{IP: net.ParseIP("2345:0425:2CA1::0567:5673:23b5"), Port: 32051}, addr.SourceAddr)
+	assert.Equal(t, &net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443}, addr.DestinationAddr)
+}
+
+func TestHeader_ReadFromV1_Unknown(t *testing.T) {
+	lines := []string{
+		"PROXY UNKNOWN\r\n",
+		"PROXY UNKNOWN ffff:f...f:ffff ffff:f...f:ffff 65535 65535\r\n",
+		"PROXY UNKNOWN junk\twith\x00anything\r\n",
+	}
+
+	for _, line := range lines {
+		reader := strings.NewReader(line + "GET / HTTP/1.1\r\n")
+
+		// Values of a previously read header must not survive
+		header := Header{Command: CommandLOCAL, ProxyAddress: headers[0].ProxyAddress, TLVs: []TLV{{TLVTypeALPN, []byte("h2")}}}
+		n, err := header.ReadFromV1(reader)
+		assert.Nil(t, err, line)
+		assert.Equal(t, len(line), int(n))
+		assert.Equal(t, CommandPROXY, header.Command)
+		assert.Nil(t, header.ProxyAddress)
+		assert.Nil(t, header.TLVs)
+		assert.True(t, header.UnknownAddresses())
+
+		// Everything up to CRLF is consumed
+		rest, err := io.ReadAll(reader)
+		assert.Nil(t, err)
+		assert.Equal(t, "GET / HTTP/1.1\r\n", string(rest))
+	}
+
+	assert.False(t, headers[0].UnknownAddresses())
+	assert.False(t, NewLocalHeader().UnknownAddresses())
+}
+
+func TestHeader_ReadFromV1_Malformed(t *testing.T) {
+	lines := []string{
+		"HELLO TCP4 192.168.0.1 192.168.0.11 56324 443\r\n",         // Wrong prefix
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\n",           // Missing CR
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443",             // Missing CRLF
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324\r\n",             // Missing port
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324 70000\r\n",       // Port out of range
+		"PROXY TCP4 192.168.0.1 2607:f0d0:1002:51::4 56324 443\r\n", // IPv6 address in TCP4
+		"PROXY TCP6 192.168.0.1 192.168.0.11 56324 443\r\n",         // IPv4 address in TCP6
+		"PROXY UDP4 192.168.0.1 192.168.0.11 56324 443\r\n",         // Unsupported protocol
+		"PROXY " + strings.Repeat("A", V1MaxLength) + "\r\n",        // Too long
+	}
+
+	for _, line := range lines {
+		var header Header
+		n, err := header.ReadFromV1(strings.NewReader(line))
+		assert.NotNil(t, err, line)
+		assert.LessOrEqual(t, int(n), V1MaxLength, line)
+	}
+}
+
+func TestHeader_WriteToV1(t *testing.T) {
+	headers := map[string]Header{
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n": {
+			Command: CommandPROXY,
+			ProxyAddress: &IPv4Address{
+				SourceAddr:      &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+				DestinationAddr: &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+			},
+		},
+		"PROXY TCP6 2607:f0d0:1002:51::4 ::ffff:127.0.0.1 56324 443\r\n": {
+			Command: CommandPROXY,
+			ProxyAddress: &IPv6Address{
+				SourceAddr:      &net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 56324},
+				DestinationAddr: &net.TCPAddr{IP: net.ParseIP("::ffff:127.0.0.1"), Port: 443},
+			},
+		},
+		"PROXY UNKNOWN\r\n": {
+			Command: CommandLOCAL,
+		},
+	}
+
+	for expected, header := range headers {
+		buffer := &bytes.Buffer{}
+		n, err := header.WriteToV1(buffer)
+		assert.Nil(t, err)
+		assert.Equal(t, len(expected), int(n))
+		assert.Equal(t, expected, buffer.String())
+
+		var decoded Header
+		_, err = decoded.ReadFromV1(buffer)
+		assert.Nil(t, err)
+	}
+}
+
+func TestHeader_WriteToV1_Unsupported(t *testing.T) {
+	header := Header{
+		Command: CommandPROXY,
+		ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.UDPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+			DestinationAddr: &net.UDPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+		},
+	}
+
+	buffer := &bytes.Buffer{}
+	_, err := header.WriteToV1(buffer)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestHeader_WriteToV1_Malformed(t *testing.T) {
+	ipv4, ipv6 := net.ParseIP("192.168.0.1"), net.ParseIP("2607:f0d0:1002:51::4")
+	addresses := map[string]ProxyAddress{
+		"nil IPv4": &IPv4Address{
+			SourceAddr:      &net.TCPAddr{Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: ipv4, Port: 443},
+		},
+		"IPv6 in TCP4": &IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: ipv4, Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: ipv6, Port: 443},
+		},
+		"invalid IP length": &IPv6Address{
+			SourceAddr:      &net.TCPAddr{IP: net.IP{0x01, 0x02, 0x03}, Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: ipv6, Port: 443},
+		},
+		"nil IPv6": &IPv6Address{
+			SourceAddr:      &net.TCPAddr{IP: ipv6, Port: 56324},
+			DestinationAddr: &net.TCPAddr{Port: 443},
+		},
+		"port above range": &IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: ipv4, Port: 1},
+			DestinationAddr: &net.TCPAddr{IP: ipv4, Port: 70000},
+		},
+		"negative port": &IPv6Address{
+			SourceAddr:      &net.TCPAddr{IP: ipv6, Port: -1},
+			DestinationAddr: &net.TCPAddr{IP: ipv6, Port: 443},
+		},
+	}
+
+	for name, address := range addresses {
+		header := Header{Command: CommandPROXY, ProxyAddress: address}
+		buffer := &bytes.Buffer{}
+		n, err := header.WriteToV1(buffer)
+		assert.NotNil(t, err, name)
+		assert.Equal(t, int64(0), n, name)
+		assert.Equal(t, 0, buffer.Len(), name)
+
+		// Version 2 rejects the same headers
+		_, err = header.WriteTo(&bytes.Buffer{})
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestHeader_MarshalText(t *testing.T) {
+	lines := []string{
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n",
+		"PROXY TCP6 2345:425:2ca1::567:5673:23b5 2607:f0d0:1002:51::4 56324 443\r\n",
+		"PROXY UNKNOWN\r\n",
+	}
+
+	for _, line := range lines {
+		var header Header
+		assert.Nil(t, header.UnmarshalText([]byte(line)))
+
+		text, err := header.MarshalText()
+		assert.Nil(t, err)
+		assert.Equal(t, line, string(text))
+	}
+}
+
+func TestHeader_MarshalText_RoundTrip(t *testing.T) {
+	headers := []Header{
+		{Command: CommandPROXY, ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: net.IPv4(192, 168, 0, 1).To4(), Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: net.IPv4(192, 168, 0, 11).To4(), Port: 443},
+		}},
+		{Command: CommandPROXY, ProxyAddress: &IPv6Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("2345:425:2ca1::567:5673:23b5"), Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("2607:f0d0:1002:51::4"), Port: 443},
+		}},
+		{Command: CommandPROXY},
+	}
+
+	for _, header := range headers {
+		text, err := header.MarshalText()
+		assert.Nil(t, err)
+
+		var decoded Header
+		assert.Nil(t, decoded.UnmarshalText(text))
+		assert.Equal(t, header, decoded, string(text))
+	}
+
+	// LOCAL would come back as PROXY UNKNOWN, so it cannot be marshaled
+	text, err := Header{Command: CommandLOCAL}.MarshalText()
+	assert.NotNil(t, err)
+	assert.Nil(t, text)
+}
+
+func TestHeader_MarshalText_Unsupported(t *testing.T) {
+	headers := []Header{
+		{Command: CommandPROXY, ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.UDPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+			DestinationAddr: &net.UDPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+		}},
+		{Command: CommandPROXY, ProxyAddress: &UnixAddr{
+			SourceAddr:      &net.UnixAddr{Name: "/tmp/source.sock", Net: "unix"},
+			DestinationAddr: &net.UnixAddr{Name: "/tmp/destination.sock", Net: "unix"},
+		}},
+		{Command: CommandPROXY, ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: net.ParseIP("192.168.0.1"), Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: net.ParseIP("192.168.0.11"), Port: 443},
+		}, TLVs: []TLV{{TLVTypeALPN, []byte("h2")}}},
+	}
+
+	for _, header := range headers {
+		_, err := header.MarshalText()
+		assert.NotNil(t, err, header.String())
+	}
+}
+
+func TestHeader_MarshalText_Malformed(t *testing.T) {
+	ipv4, ipv6 := net.ParseIP("192.168.0.1"), net.ParseIP("2607:f0d0:1002:51::4")
+	headers := map[string]Header{
+		"nil IP": {Command: CommandPROXY, ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: nil, Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: ipv4, Port: 443},
+		}},
+		"mismatched IP family": {Command: CommandPROXY, ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: ipv6, Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: ipv4, Port: 443},
+		}},
+		"out of range port": {Command: CommandPROXY, ProxyAddress: &IPv4Address{
+			SourceAddr:      &net.TCPAddr{IP: ipv4, Port: 56324},
+			DestinationAddr: &net.TCPAddr{IP: ipv4, Port: 70000},
+		}},
+	}
+
+	for name, header := range headers {
+		text, err := header.MarshalText()
+		assert.NotNil(t, err, name)
+		assert.Nil(t, text, name)
+	}
+}
+
+func TestHeader_UnmarshalText_Invalid(t *testing.T) {
+	original := *headers[0]
+	header := original
+
+	texts := []string{
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\nGET /",
+		"PROXY TCP4 192.168.0.1 192.168.0.11 56324 443",
+		"PROXY UDP4 192.168.0.1 192.168.0.11 56324 443\r\n",
+	}
+
+	for _, text := range texts {
+		assert.NotNil(t, header.UnmarshalText([]byte(text)), text)
+		assert.Equal(t, original, header)
+	}
+}
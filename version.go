@@ -1,6 +1,7 @@
 package haproxy
 
 import (
+	"fmt"
 	"io"
 )
 
@@ -13,25 +14,86 @@ const (
 	CommandPROXY
 )
 
+func (c Command) String() string {
+	switch c {
+	case CommandLOCAL:
+		return "LOCAL"
+	case CommandPROXY:
+		return "PROXY"
+	default:
+		return fmt.Sprintf("UNKNOWN(0x%02x)", byte(c))
+	}
+}
+
 type VersionByte struct {
 	ProtocolVersion byte
 	Command         Command
 }
 
+// NewVersionByte returns the version byte for cmd with ProtocolVersion set,
+// as every conforming sender must emit it.
+func NewVersionByte(cmd Command) VersionByte {
+	return VersionByte{
+		ProtocolVersion: ProtocolVersion,
+		Command:         cmd,
+	}
+}
+
+// Validate returns *UnsupportedVersionError if the version is not
+// ProtocolVersion, and *UnsupportedCommandError if the command is neither
+// CommandLOCAL nor CommandPROXY.
+func (v VersionByte) Validate() error {
+	// As of this specification, it must always be sent as \x2 and the receiver must only accept this value.
+	if v.ProtocolVersion != ProtocolVersion {
+		return &UnsupportedVersionError{v.ProtocolVersion}
+	}
+
+	// Other values are unassigned and must not be emitted by senders. Receivers
+	// must drop connections presenting unexpected values here.
+	if v.Command != CommandLOCAL && v.Command != CommandPROXY {
+		return &UnsupportedCommandError{v.Command}
+	}
+
+	return nil
+}
+
 func (v *VersionByte) ReadFrom(r io.Reader) (n int64, err error) {
-	data := make([]byte, 1)
-	m, err := r.Read(data)
-	n += int64(m)
+	b, n, err := readByte(r)
 	if err != nil {
 		return n, err
 	}
 
-	v.ProtocolVersion = data[0] >> 4
-	v.Command = Command(data[0] & 0b1111)
+	v.ProtocolVersion = b >> 4
+	v.Command = Command(b & 0b1111)
 	return
 }
 
+// readByte reads a single byte, avoiding the allocation of a buffer for it if
+// r is an io.ByteReader, like bufio.Reader. It only serves the standalone
+// VersionByte.ReadFrom and ProtocolByte.ReadFrom, Header.ReadFrom parses both
+// bytes from the fixed part of the header it reads at once.
+func readByte(r io.Reader) (byte, int64, error) {
+	if br, ok := r.(io.ByteReader); ok {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+
+		return b, 1, nil
+	}
+
+	// Readers may return no data without an error, which must not be taken
+	// for a zero byte
+	data := make([]byte, 1)
+	m, err := io.ReadFull(r, data)
+	if err != nil {
+		return 0, int64(m), err
+	}
+
+	return data[0], int64(m), nil
+}
+
 func (v VersionByte) WriteTo(w io.Writer) (n int64, err error) {
-	m, err := w.Write([]byte{v.ProtocolVersion<<4 | byte(v.Command)})
+	m, err := writeFull(w, []byte{v.ProtocolVersion<<4 | byte(v.Command)})
 	return int64(m), err
 }
@@ -0,0 +1,92 @@
+package haproxy
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionByte_ReadFrom(t *testing.T) {
+	readers := []io.Reader{
+		bufio.NewReader(bytes.NewReader([]byte{0x21})),
+		iotest.OneByteReader(bytes.NewReader([]byte{0x21})),
+	}
+
+	for _, r := range readers {
+		var version VersionByte
+		n, err := version.ReadFrom(r)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1), n)
+		assert.Equal(t, VersionByte{ProtocolVersion: 2, Command: CommandPROXY}, version)
+
+		n, err = version.ReadFrom(r)
+		assert.Equal(t, io.EOF, err)
+		assert.Equal(t, int64(0), n)
+	}
+}
+
+// emptyReadsReader returns no data without an error a number of times before
+// reading from the underlying reader, which io.Reader allows.
+type emptyReadsReader struct {
+	io.Reader
+	empty int
+}
+
+func (e *emptyReadsReader) Read(p []byte) (int, error) {
+	if e.empty > 0 {
+		e.empty--
+		return 0, nil
+	}
+
+	return e.Reader.Read(p)
+}
+
+func TestVersionByte_ReadFrom_EmptyReads(t *testing.T) {
+	var version VersionByte
+	n, err := version.ReadFrom(&emptyReadsReader{Reader: bytes.NewReader([]byte{0x21}), empty: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, VersionByte{ProtocolVersion: 2, Command: CommandPROXY}, version)
+
+	var protocol ProtocolByte
+	n, err = protocol.ReadFrom(&emptyReadsReader{Reader: bytes.NewReader([]byte{0x21}), empty: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, ProtocolByte{AddressFamilyINET6, TransportProtocolSTREAM}, protocol)
+}
+
+func TestNewVersionByte(t *testing.T) {
+	version := NewVersionByte(CommandPROXY)
+	assert.Equal(t, VersionByte{ProtocolVersion: 0x2, Command: CommandPROXY}, version)
+	assert.Nil(t, version.Validate())
+	assert.Nil(t, NewVersionByte(CommandLOCAL).Validate())
+}
+
+func TestVersionByte_Validate(t *testing.T) {
+	err := VersionByte{ProtocolVersion: 0x1, Command: CommandPROXY}.Validate()
+	assert.Equal(t, &UnsupportedVersionError{0x1}, err)
+
+	err = NewVersionByte(Command(0x05)).Validate()
+	assert.Equal(t, &UnsupportedCommandError{Command(0x05)}, err)
+}
+
+func BenchmarkVersionByte_ReadFrom(b *testing.B) {
+	b.ReportAllocs()
+	data := bytes.Repeat([]byte{0x21}, 4096)
+	reader := bufio.NewReader(nil)
+	var version VersionByte
+	for i := 0; i < b.N; i++ {
+		if i%len(data) == 0 {
+			reader.Reset(bytes.NewReader(data))
+		}
+
+		_, err := version.ReadFrom(reader)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}